@@ -0,0 +1,46 @@
+package sstable
+
+// arenaChunkSize is the size of each chunk an arena allocates from. Allocations larger than a chunk are given a
+// chunk of their own.
+const arenaChunkSize = 1 << 20
+
+// arenaRef locates an allocation within an arena, holding the chunk index in the upper 32 bits and the offset
+// within the chunk in the lower 32 bits. Refs are plain integers, so data linked by them holds no pointers for the
+// garbage collector to trace.
+type arenaRef uint64
+
+// arena hands out byte slices carved from large chunks, so that many small allocations cost the garbage collector
+// a handful of pointer free objects. Memory is only released when the whole arena is.
+type arena struct {
+	chunks [][]byte
+	// size is the capacity of every chunk allocated, including the unused tail of each chunk
+	size int64
+}
+
+// alloc reserves n zeroed bytes and returns a ref to them.
+func (a *arena) alloc(n int) arenaRef {
+	last := len(a.chunks) - 1
+	if last < 0 || len(a.chunks[last])+n > cap(a.chunks[last]) {
+		a.chunks = append(a.chunks, make([]byte, 0, max(arenaChunkSize, n)))
+		last++
+		a.size += int64(cap(a.chunks[last]))
+	}
+	chunk := a.chunks[last]
+	offset := len(chunk)
+	a.chunks[last] = chunk[:offset+n]
+	return arenaRef(uint64(last)<<32 | uint64(offset))
+}
+
+// bytes returns the n bytes at ref, which must lie within a single allocation.
+func (a *arena) bytes(ref arenaRef, n int) []byte {
+	chunk := a.chunks[ref>>32]
+	offset := int(uint32(ref))
+	return chunk[offset : offset+n : offset+n]
+}
+
+// copyBytes allocates a copy of contents in the arena, returning its ref.
+func (a *arena) copyBytes(contents []byte) arenaRef {
+	ref := a.alloc(len(contents))
+	copy(a.bytes(ref, len(contents)), contents)
+	return ref
+}
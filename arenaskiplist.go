@@ -0,0 +1,343 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Each ArenaSkipList node is laid out in its arena as:
+//
+//	atomic count (8 bytes) | value ref (8 bytes) | value length (4 bytes) | key length (4 bytes) |
+//	kind (1 byte) | height (1 byte) | padding (6 bytes) | next refs (8 bytes per level) | key bytes
+//
+// with the value stored in an allocation of its own so that it can be replaced when the key is written again.
+const (
+	arenaNodeCountOffset    = 0
+	arenaNodeValueRefOffset = 8
+	arenaNodeValueLenOffset = 16
+	arenaNodeKeyLenOffset   = 20
+	arenaNodeKindOffset     = 24
+	arenaNodeHeightOffset   = 25
+	arenaNodeHeaderSize     = 32
+)
+
+// ArenaSkipList is a memtable which copies every key and value into large arena chunks and links its nodes with
+// arena offsets rather than pointers. Inserts make no heap allocations of their own beyond the occasional new
+// chunk, and the garbage collector has no per-entry pointers to trace, however many entries are held. Like Bst
+// it is not safe for concurrent use.
+//
+// Keys and values are copied on insert, so callers may reuse their buffers. Records returned by lookups, scans and
+// iterators alias the arena and remain valid for as long as they are referenced.
+//
+// Writing a key which is already present keeps whichever write has the higher atomic count, so a delete only
+// shadows inserts older than it. Replaced values are not reclaimed until the whole memtable is released.
+//
+// Key and value lengths are stored as uint32, so Insert and Delete panic with ErrRecordTooLarge when given a key or
+// value larger than MaxKeySize or MaxValueSize.
+type ArenaSkipList struct {
+	arena arena
+	// head is the first allocation in the arena, so its ref is zero, which next refs use to mean no node as the
+	// head is never linked to
+	head           arenaRef
+	height         int
+	count          int
+	flushThreshold int64
+}
+
+func NewArenaSkipList() *ArenaSkipList {
+	return NewArenaSkipListWithOptions(DefaultMemtableOptions())
+}
+
+// NewArenaSkipListWithOptions creates an empty list which signals a flush according to opts. A nil opts is the same
+// as DefaultMemtableOptions.
+func NewArenaSkipListWithOptions(opts *MemtableOptions) *ArenaSkipList {
+	if opts == nil {
+		opts = DefaultMemtableOptions()
+	}
+	s := &ArenaSkipList{height: 1, flushThreshold: opts.FlushThreshold}
+	s.head = s.newNode(nil, skipListMaxHeight)
+	return s
+}
+
+// newNode allocates a node of the given height holding a copy of key.
+func (s *ArenaSkipList) newNode(key []byte, height int) arenaRef {
+	keyOffset := arenaNodeHeaderSize + 8*height
+	keyLen := arenaLength(len(key), MaxKeySize)
+	node := s.arena.alloc(keyOffset + len(key))
+	contents := s.arena.bytes(node, keyOffset+len(key))
+	byteOrdering.PutUint32(contents[arenaNodeKeyLenOffset:], keyLen)
+	contents[arenaNodeHeightOffset] = byte(height)
+	copy(contents[keyOffset:], key)
+	return node
+}
+
+// arenaLength returns n as the uint32 stored in a node, panicking with ErrRecordTooLarge if it is larger than limit.
+func arenaLength(n int, limit uint64) uint32 {
+	if uint64(n) > limit {
+		panic(fmt.Errorf("%w: %d bytes", ErrRecordTooLarge, n))
+	}
+	return uint32(n)
+}
+
+func (s *ArenaSkipList) header(node arenaRef) []byte {
+	return s.arena.bytes(node, arenaNodeHeaderSize)
+}
+
+func (s *ArenaSkipList) next(node arenaRef, level int) arenaRef {
+	return arenaRef(byteOrdering.Uint64(s.arena.bytes(node+arenaNodeHeaderSize+arenaRef(8*level), 8)))
+}
+
+func (s *ArenaSkipList) setNext(node arenaRef, level int, next arenaRef) {
+	byteOrdering.PutUint64(s.arena.bytes(node+arenaNodeHeaderSize+arenaRef(8*level), 8), uint64(next))
+}
+
+func (s *ArenaSkipList) key(node arenaRef) []byte {
+	header := s.header(node)
+	keyOffset := arenaNodeHeaderSize + 8*int(header[arenaNodeHeightOffset])
+	return s.arena.bytes(node+arenaRef(keyOffset), int(byteOrdering.Uint32(header[arenaNodeKeyLenOffset:])))
+}
+
+func (s *ArenaSkipList) value(node arenaRef) []byte {
+	header := s.header(node)
+	valueRef := arenaRef(byteOrdering.Uint64(header[arenaNodeValueRefOffset:]))
+	return s.arena.bytes(valueRef, int(byteOrdering.Uint32(header[arenaNodeValueLenOffset:])))
+}
+
+func (s *ArenaSkipList) kind(node arenaRef) RecordKind {
+	return RecordKind(s.header(node)[arenaNodeKindOffset])
+}
+
+func (s *ArenaSkipList) record(node arenaRef) *Record {
+	header := s.header(node)
+	rec := NewRecordWithCount(s.key(node), s.value(node), byteOrdering.Uint64(header[arenaNodeCountOffset:]))
+	rec.Kind = RecordKind(header[arenaNodeKindOffset])
+	return rec
+}
+
+// setEntry stores a copy of value in the arena and records it as the node's current write.
+func (s *ArenaSkipList) setEntry(node arenaRef, kind RecordKind, value []byte, atomicCount uint64) {
+	valueLen := arenaLength(len(value), MaxValueSize)
+	var valueRef arenaRef
+	if len(value) > 0 {
+		valueRef = s.arena.copyBytes(value)
+	}
+	header := s.header(node)
+	byteOrdering.PutUint64(header[arenaNodeCountOffset:], atomicCount)
+	byteOrdering.PutUint64(header[arenaNodeValueRefOffset:], uint64(valueRef))
+	byteOrdering.PutUint32(header[arenaNodeValueLenOffset:], valueLen)
+	header[arenaNodeKindOffset] = byte(kind)
+}
+
+// Len returns the number of distinct keys in the list, including deleted keys.
+func (s *ArenaSkipList) Len() int {
+	return s.count
+}
+
+// ApproximateSize returns the capacity of the arena's chunks, which covers values which have since been replaced
+// and the unused space at the end of each chunk.
+func (s *ArenaSkipList) ApproximateSize() int64 {
+	return s.arena.size
+}
+
+func (s *ArenaSkipList) ShouldFlush() bool {
+	return shouldFlush(s.arena.size, s.flushThreshold)
+}
+
+func (s *ArenaSkipList) Insert(key, value []byte, atomicCount uint64) {
+	s.insert(KindPut, key, value, atomicCount)
+}
+
+func (s *ArenaSkipList) Delete(key []byte, atomicCount uint64) {
+	s.insert(KindDelete, key, nil, atomicCount)
+}
+
+func (s *ArenaSkipList) insert(kind RecordKind, key, value []byte, atomicCount uint64) {
+	var prev [skipListMaxHeight]arenaRef
+	node := s.head
+	for level := s.height - 1; level >= 0; level-- {
+		node = s.findSpliceForLevel(key, level, node)
+		prev[level] = node
+	}
+	if next := s.next(prev[0], 0); next != 0 && bytes.Equal(s.key(next), key) {
+		if atomicCount > byteOrdering.Uint64(s.header(next)[arenaNodeCountOffset:]) {
+			s.setEntry(next, kind, value, atomicCount)
+		}
+		return
+	}
+
+	height := randomHeight()
+	for ; s.height < height; s.height++ {
+		prev[s.height] = s.head
+	}
+	node = s.newNode(key, height)
+	s.setEntry(node, kind, value, atomicCount)
+	for level := 0; level < height; level++ {
+		s.setNext(node, level, s.next(prev[level], level))
+		s.setNext(prev[level], level, node)
+	}
+	s.count++
+}
+
+// findSpliceForLevel returns the last node on level, starting from start, with a key less than key.
+func (s *ArenaSkipList) findSpliceForLevel(key []byte, level int, start arenaRef) arenaRef {
+	prev := start
+	for {
+		next := s.next(prev, level)
+		if next == 0 || bytes.Compare(s.key(next), key) >= 0 {
+			return prev
+		}
+		prev = next
+	}
+}
+
+// seekLT returns the last node with a key less than key, which is the head if there is none.
+func (s *ArenaSkipList) seekLT(key []byte) arenaRef {
+	node := s.head
+	for level := s.height - 1; level >= 0; level-- {
+		node = s.findSpliceForLevel(key, level, node)
+	}
+	return node
+}
+
+func (s *ArenaSkipList) search(key []byte) arenaRef {
+	node := s.next(s.seekLT(key), 0)
+	if node == 0 || !bytes.Equal(s.key(node), key) {
+		return 0
+	}
+	return node
+}
+
+func (s *ArenaSkipList) Contains(key []byte) (bool, error) {
+	node := s.search(key)
+	return node != 0 && s.kind(node) != KindDelete, nil
+}
+
+func (s *ArenaSkipList) Get(key []byte) (*Record, error) {
+	node := s.search(key)
+	if node == 0 {
+		return nil, ErrNotFound
+	}
+	if s.kind(node) == KindDelete {
+		return nil, ErrDeleted
+	}
+	return s.record(node), nil
+}
+
+func (s *ArenaSkipList) Scan() ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, nil, nil, false)
+}
+
+func (s *ArenaSkipList) ScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, nil, limit, false)
+}
+
+func (s *ArenaSkipList) ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, pred, limit, false)
+}
+
+func (s *ArenaSkipList) ReverseScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, nil, limit, true)
+}
+
+func (s *ArenaSkipList) ReverseScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, pred, limit, true)
+}
+
+func (s *ArenaSkipList) ScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), r, nil, limit, false)
+}
+
+func (s *ArenaSkipList) ReverseScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), r, nil, limit, true)
+}
+
+func (s *ArenaSkipList) ToSSTable(tableName string) *SSTable {
+	records := make([]*Record, 0, s.count)
+	for node := s.next(s.head, 0); node != 0; node = s.next(node, 0) {
+		records = append(records, s.record(node))
+	}
+	return NewSSTable(tableName, records)
+}
+
+// NewIterator returns an iterator over the list. Stepping forwards follows the bottom level, while stepping
+// backwards searches from the top for the preceding key. The list must not be modified while the iterator is in
+// use.
+func (s *ArenaSkipList) NewIterator() Iterator {
+	return &arenaSkipListIterator{list: s}
+}
+
+type arenaSkipListIterator struct {
+	list *ArenaSkipList
+	// current is zero when the iterator is not positioned on a node
+	current arenaRef
+}
+
+// move positions the iterator on node, then steps past any tombstones in the given direction.
+func (it *arenaSkipListIterator) move(node arenaRef, forward bool) bool {
+	for node != 0 && it.list.kind(node) == KindDelete {
+		if forward {
+			node = it.list.next(node, 0)
+		} else {
+			node = it.list.seekLT(it.list.key(node))
+		}
+	}
+	it.current = node
+	return it.current != 0
+}
+
+func (it *arenaSkipListIterator) SeekGE(key []byte) bool {
+	return it.move(it.list.next(it.list.seekLT(key), 0), true)
+}
+
+func (it *arenaSkipListIterator) SeekLT(key []byte) bool {
+	return it.move(it.list.seekLT(key), false)
+}
+
+func (it *arenaSkipListIterator) First() bool {
+	return it.move(it.list.next(it.list.head, 0), true)
+}
+
+func (it *arenaSkipListIterator) Last() bool {
+	node := it.list.head
+	for level := it.list.height - 1; level >= 0; level-- {
+		for next := it.list.next(node, level); next != 0; next = it.list.next(node, level) {
+			node = next
+		}
+	}
+	return it.move(node, false)
+}
+
+func (it *arenaSkipListIterator) Next() bool {
+	if it.current == 0 {
+		return false
+	}
+	return it.move(it.list.next(it.current, 0), true)
+}
+
+func (it *arenaSkipListIterator) Prev() bool {
+	if it.current == 0 {
+		return false
+	}
+	return it.move(it.list.seekLT(it.list.key(it.current)), false)
+}
+
+func (it *arenaSkipListIterator) Key() []byte {
+	return it.list.key(it.current)
+}
+
+func (it *arenaSkipListIterator) Value() []byte {
+	return it.list.value(it.current)
+}
+
+func (it *arenaSkipListIterator) Record() *Record {
+	return it.list.record(it.current)
+}
+
+func (it *arenaSkipListIterator) Error() error {
+	return nil
+}
+
+func (it *arenaSkipListIterator) Close() error {
+	it.current = 0
+	return nil
+}
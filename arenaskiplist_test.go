@@ -0,0 +1,52 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestArenaSkipList_CopiesInput(t *testing.T) {
+	list := NewArenaSkipList()
+	key := make([]byte, 8)
+	value := make([]byte, 64)
+	for i := 0; i < 50000; i++ {
+		copy(key, fmt.Sprintf("key-%04d", i%10000))
+		copy(value, fmt.Sprintf("%064d", i))
+		list.Insert(key, value, uint64(i))
+	}
+	// a value larger than a chunk is given a chunk of its own
+	large := bytes.Repeat([]byte("x"), arenaChunkSize+1)
+	list.Insert([]byte("large"), large, 1)
+
+	assert.Equal(t, 10001, list.Len())
+	assert.Greater(t, len(list.arena.chunks), 2)
+	// the size counts whole chunks, including the tail left unused when the large value needed a chunk of its own
+	var chunkCapacity int64
+	for _, chunk := range list.arena.chunks {
+		chunkCapacity += int64(cap(chunk))
+	}
+	assert.Equal(t, chunkCapacity, list.ApproximateSize())
+	for i := 0; i < 10000; i++ {
+		rec, err := list.Get([]byte(fmt.Sprintf("key-%04d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("%064d", 40000+i)), rec.Value)
+	}
+	rec, err := list.Get([]byte("large"))
+	require.NoError(t, err)
+	assert.Equal(t, large, rec.Value)
+}
+
+func TestArenaLength(t *testing.T) {
+	assert.Equal(t, uint32(1024), arenaLength(1024, MaxValueSize))
+	tooLarge := uint64(math.MaxUint32) + 1
+	if tooLarge > math.MaxInt {
+		t.Skip("lengths beyond MaxUint32 do not fit in an int on this platform")
+	}
+	assert.PanicsWithError(t, fmt.Sprintf("%s: %d bytes", ErrRecordTooLarge, tooLarge), func() {
+		arenaLength(int(tooLarge), MaxKeySize)
+	})
+}
@@ -0,0 +1,352 @@
+package sstable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// blockTrailerSize is the size of the compression type and checksum stored after every block.
+const blockTrailerSize = 1 + 4
+
+// blockHandle locates a block within a table file, including its trailer.
+type blockHandle struct {
+	Offset uint64
+	Size   uint64
+}
+
+// indexEntry points at a data block along with the last key stored in it, so a lookup only needs to binary
+// search the index to find the single block which could contain a key.
+type indexEntry struct {
+	LastKey []byte
+	Handle  blockHandle
+}
+
+// searchIndex returns the position of the only block which could contain key, or len(index) if key is greater
+// than every key in the table.
+func searchIndex(index []indexEntry, key []byte) int {
+	return sort.Search(len(index), func(i int) bool {
+		return bytes.Compare(index[i].LastKey, key) >= 0
+	})
+}
+
+// frameBlock compresses a block with codec, keeping it uncompressed if that does not make it smaller, and
+// appends the trailer recording the compression type and a checksum of the stored bytes.
+func frameBlock(raw []byte, codec Codec) ([]byte, error) {
+	compressionType := NoCompression
+	if codec != nil && codec.Type() != NoCompression {
+		compressed, err := codec.Compress(raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(compressed) < len(raw) {
+			raw = compressed
+			compressionType = codec.Type()
+		}
+	}
+	contents := make([]byte, 0, len(raw)+blockTrailerSize)
+	contents = append(contents, raw...)
+	contents = append(contents, byte(compressionType))
+	return appendChecksum(contents), nil
+}
+
+// unframeBlock verifies the trailer written by frameBlock and returns the decompressed block.
+func unframeBlock(contents []byte) ([]byte, error) {
+	payload, err := stripChecksum(contents)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < 1 {
+		return nil, newCorruptionError(0, "block compression type is missing")
+	}
+	codec, err := codecFor(CompressionType(payload[len(payload)-1]))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := codec.Decompress(payload[:len(payload)-1])
+	if err != nil {
+		return nil, newCorruptionError(0, "block failed to decompress: "+err.Error())
+	}
+	return raw, nil
+}
+
+// blockBuilder accumulates sorted records into a single data block. Keys are prefix compressed against the
+// previous key, except at restart points every RestartInterval entries where the full key is written. The offsets
+// of the restart points are stored at the end of the block so lookups can binary search them.
+//
+// Each entry is laid out as:
+//
+//	shared key length (uvarint) | unshared key length (uvarint) | value length (uvarint) |
+//	kind (1 byte) | atomic count (uvarint) | unshared key bytes | value bytes
+//
+// and the block ends with the restart offsets and their count, each a little endian uint32.
+type blockBuilder struct {
+	restartInterval int
+	buf             []byte
+	restarts        []uint32
+	counter         int
+	lastKey         []byte
+}
+
+func newBlockBuilder(restartInterval int) *blockBuilder {
+	if restartInterval < 1 {
+		restartInterval = 1
+	}
+	return &blockBuilder{restartInterval: restartInterval}
+}
+
+// add appends rec to the block. Restart offsets are stored as uint32, so it returns ErrRecordTooLarge rather than
+// start a restart point beyond them, which can only happen when a block grows past 4 GiB.
+func (b *blockBuilder) add(rec *Record) error {
+	shared := 0
+	if b.counter < b.restartInterval && len(b.restarts) > 0 {
+		shared = sharedPrefixLen(b.lastKey, rec.Key)
+	} else {
+		if uint64(len(b.buf)) > math.MaxUint32 {
+			return fmt.Errorf("%w: restart point at offset %d of a block", ErrRecordTooLarge, len(b.buf))
+		}
+		b.restarts = append(b.restarts, uint32(len(b.buf)))
+		b.counter = 0
+	}
+
+	b.buf = binary.AppendUvarint(b.buf, uint64(shared))
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(rec.Key)-shared))
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(rec.Value)))
+	b.buf = append(b.buf, byte(rec.Kind))
+	b.buf = binary.AppendUvarint(b.buf, rec.AtomicCount)
+	b.buf = append(b.buf, rec.Key[shared:]...)
+	b.buf = append(b.buf, rec.Value...)
+	b.lastKey = append(b.lastKey[:0], rec.Key...)
+	b.counter++
+	return nil
+}
+
+func (b *blockBuilder) empty() bool {
+	return len(b.buf) == 0
+}
+
+// estimatedSize is the size the block would have on disk if it were finished now without compression.
+func (b *blockBuilder) estimatedSize() int {
+	return len(b.buf) + 4*len(b.restarts) + 4 + blockTrailerSize
+}
+
+// finish returns the uncompressed block contents and resets the builder.
+func (b *blockBuilder) finish() []byte {
+	contents := b.buf
+	for _, restart := range b.restarts {
+		contents = byteOrdering.AppendUint32(contents, restart)
+	}
+	contents = byteOrdering.AppendUint32(contents, uint32(len(b.restarts)))
+
+	b.buf = nil
+	b.restarts = nil
+	b.counter = 0
+	return contents
+}
+
+func sharedPrefixLen(a, b []byte) int {
+	n := minInt(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// block is a verified data block, ready to be searched.
+type block struct {
+	data     []byte
+	restarts []uint32
+}
+
+// parseBlock locates the restart points of an uncompressed data block.
+func parseBlock(payload []byte) (*block, error) {
+	if len(payload) < 4 {
+		return nil, newCorruptionError(0, "block restart count is truncated")
+	}
+	numRestarts := byteOrdering.Uint32(payload[len(payload)-4:])
+	restartsOffset := uint64(len(payload)) - 4 - 4*uint64(numRestarts)
+	if 4*uint64(numRestarts)+4 > uint64(len(payload)) {
+		return nil, newCorruptionError(int64(len(payload)-4), "block restart count exceeds block size")
+	}
+
+	b := &block{
+		data:     payload[:restartsOffset],
+		restarts: make([]uint32, numRestarts),
+	}
+	for i := range b.restarts {
+		b.restarts[i] = byteOrdering.Uint32(payload[restartsOffset+4*uint64(i):])
+		if int(b.restarts[i]) > len(b.data) {
+			return nil, newCorruptionError(int64(restartsOffset)+4*int64(i), "block restart point out of range")
+		}
+	}
+	return b, nil
+}
+
+// entryAt decodes the entry at offset, rebuilding its key from the previous key in the block. It returns the
+// record along with the offset of the following entry.
+func (b *block) entryAt(offset int, prevKey []byte) (*Record, int, error) {
+	start := offset
+	var header [3]uint64
+	for i := range header {
+		value, n := binary.Uvarint(b.data[offset:])
+		if n <= 0 {
+			return nil, 0, newCorruptionError(int64(start), "block entry header is truncated")
+		}
+		header[i] = value
+		offset += n
+	}
+	shared, unshared, valueSize := header[0], header[1], header[2]
+	if shared > uint64(len(prevKey)) || offset >= len(b.data) {
+		return nil, 0, newCorruptionError(int64(start), "block entry header is invalid")
+	}
+	kind := RecordKind(b.data[offset])
+	if !kind.valid() {
+		return nil, 0, newCorruptionError(int64(start), "block entry kind is unknown")
+	}
+	offset++
+	count, n := binary.Uvarint(b.data[offset:])
+	if n <= 0 {
+		return nil, 0, newCorruptionError(int64(start), "block entry count is truncated")
+	}
+	offset += n
+	// the sizes are checked one at a time against what is left of the block, as their sum may overflow
+	remaining := uint64(len(b.data) - offset)
+	if unshared > remaining || valueSize > remaining-unshared {
+		return nil, 0, newCorruptionError(int64(start), "block entry runs past end of block")
+	}
+
+	key := make([]byte, 0, shared+unshared)
+	key = append(key, prevKey[:shared]...)
+	key = append(key, b.data[offset:offset+int(unshared)]...)
+	offset += int(unshared)
+	value := b.data[offset : offset+int(valueSize)]
+	offset += int(valueSize)
+
+	rec := NewRecordWithCount(key, value, count)
+	rec.Kind = kind
+	return rec, offset, nil
+}
+
+// seek returns the record with the given key, or nil if the block does not contain it. The restart points are
+// binary searched for the last one with a key no greater than key, and entries are then decoded from there.
+func (b *block) seek(key []byte) (*Record, error) {
+	var searchErr error
+	restartIdx := sort.Search(len(b.restarts), func(i int) bool {
+		rec, _, err := b.entryAt(int(b.restarts[i]), nil)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return bytes.Compare(rec.Key, key) > 0
+	}) - 1
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	if restartIdx < 0 {
+		return nil, nil
+	}
+
+	var prevKey []byte
+	for offset := int(b.restarts[restartIdx]); offset < len(b.data); {
+		rec, next, err := b.entryAt(offset, prevKey)
+		if err != nil {
+			return nil, err
+		}
+		cmp := bytes.Compare(rec.Key, key)
+		if cmp == 0 {
+			return rec, nil
+		}
+		if cmp > 0 {
+			return nil, nil
+		}
+		prevKey = rec.Key
+		offset = next
+	}
+	return nil, nil
+}
+
+// records decodes every record in the block in key order.
+func (b *block) records() ([]*Record, error) {
+	var records []*Record
+	var prevKey []byte
+	for offset := 0; offset < len(b.data); {
+		rec, next, err := b.entryAt(offset, prevKey)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+		prevKey = rec.Key
+		offset = next
+	}
+	return records, nil
+}
+
+// encodeIndex writes each entry as the length of its last key, the key itself and the block handle, with every
+// integer stored as a uvarint.
+func encodeIndex(entries []indexEntry) []byte {
+	var contents []byte
+	for _, entry := range entries {
+		contents = binary.AppendUvarint(contents, uint64(len(entry.LastKey)))
+		contents = append(contents, entry.LastKey...)
+		contents = binary.AppendUvarint(contents, entry.Handle.Offset)
+		contents = binary.AppendUvarint(contents, entry.Handle.Size)
+	}
+	return contents
+}
+
+// decodeIndex decodes the index of a table written with the given format version.
+func decodeIndex(payload []byte, version uint32) ([]indexEntry, error) {
+	if version == 1 {
+		return decodeIndexV1(payload)
+	}
+	var entries []indexEntry
+	offset := 0
+	for offset < len(payload) {
+		start := offset
+		keySize, n := binary.Uvarint(payload[offset:])
+		if n <= 0 || keySize > uint64(len(payload)-offset-n) {
+			return nil, newCorruptionError(int64(start), "index entry key is truncated")
+		}
+		offset += n
+		entry := indexEntry{LastKey: payload[offset : offset+int(keySize)]}
+		offset += int(keySize)
+		for _, field := range []*uint64{&entry.Handle.Offset, &entry.Handle.Size} {
+			*field, n = binary.Uvarint(payload[offset:])
+			if n <= 0 {
+				return nil, newCorruptionError(int64(start), "index entry handle is truncated")
+			}
+			offset += n
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// decodeIndexV1 decodes the index of a version 1 table, where the key length and the block handle were stored as
+// fixed size 32-bit integers.
+func decodeIndexV1(payload []byte) ([]indexEntry, error) {
+	var entries []indexEntry
+	offset := 0
+	for offset < len(payload) {
+		start := offset
+		if offset+4 > len(payload) {
+			return nil, newCorruptionError(int64(start), "index entry is truncated")
+		}
+		keySize := byteOrdering.Uint32(payload[offset:])
+		offset += 4
+		if uint64(offset)+uint64(keySize)+8 > uint64(len(payload)) {
+			return nil, newCorruptionError(int64(start), "index entry is truncated")
+		}
+		entry := indexEntry{LastKey: payload[offset : offset+int(keySize)]}
+		offset += int(keySize)
+		entry.Handle.Offset = uint64(byteOrdering.Uint32(payload[offset:]))
+		entry.Handle.Size = uint64(byteOrdering.Uint32(payload[offset+4:]))
+		offset += 8
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,94 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestBlock_Seek(t *testing.T) {
+	for _, restartInterval := range []int{1, 2, 16} {
+		t.Run(fmt.Sprintf("restart interval %d", restartInterval), func(t *testing.T) {
+			builder := newBlockBuilder(restartInterval)
+			var records []*Record
+			for i := 0; i < 50; i++ {
+				rec := NewRecordWithCount([]byte(fmt.Sprintf("tenant/entity/%04d", i*2)), []byte("value"), uint64(i))
+				records = append(records, rec)
+				require.NoError(t, builder.add(rec))
+			}
+			require.NoError(t, builder.add(NewTombstoneWithCount([]byte("tenant/entity/9999"), 100)))
+
+			blk, err := parseBlock(builder.finish())
+			require.NoError(t, err)
+
+			for i, rec := range records {
+				found, err := blk.seek(rec.Key)
+				require.NoError(t, err)
+				assert.Equal(t, rec, found)
+
+				missing, err := blk.seek([]byte(fmt.Sprintf("tenant/entity/%04d", i*2+1)))
+				require.NoError(t, err)
+				assert.Nil(t, missing)
+			}
+
+			tombstone, err := blk.seek([]byte("tenant/entity/9999"))
+			require.NoError(t, err)
+			assert.True(t, tombstone.Deleted())
+
+			all, err := blk.records()
+			require.NoError(t, err)
+			assert.Equal(t, records, all[:len(records)])
+		})
+	}
+}
+
+func TestBlock_OversizedEntry(t *testing.T) {
+	// an unshared key size which overflows when added to the value size must not pass the bounds check
+	var contents []byte
+	for _, v := range []uint64{0, math.MaxUint64, 2} {
+		contents = binary.AppendUvarint(contents, v)
+	}
+	contents = append(contents, byte(KindPut))
+	contents = binary.AppendUvarint(contents, 1)
+	contents = append(contents, "ab"...)
+	contents = byteOrdering.AppendUint32(contents, 0)
+	contents = byteOrdering.AppendUint32(contents, 1)
+
+	blk, err := parseBlock(contents)
+	require.NoError(t, err)
+	var corrupt *CorruptionError
+	_, err = blk.records()
+	assert.ErrorAs(t, err, &corrupt)
+	_, err = blk.seek([]byte("a"))
+	assert.ErrorAs(t, err, &corrupt)
+}
+
+func TestBlockBuilder_PrefixCompression(t *testing.T) {
+	uncompressed := newBlockBuilder(1)
+	compressed := newBlockBuilder(DefaultRestartInterval)
+	for i := 0; i < 100; i++ {
+		rec := NewRecordWithCount([]byte(fmt.Sprintf("tenant-0001/entity-0001/%010d", i)), []byte("v"), uint64(i))
+		require.NoError(t, uncompressed.add(rec))
+		require.NoError(t, compressed.add(rec))
+	}
+	assert.Less(t, compressed.estimatedSize()*2, uncompressed.estimatedSize())
+}
+
+func TestIndex_LargeOffsets(t *testing.T) {
+	entries := []indexEntry{
+		{LastKey: []byte("a"), Handle: blockHandle{Offset: 0, Size: 4096}},
+		{LastKey: []byte("b"), Handle: blockHandle{Offset: 5 << 30, Size: 4096}},
+		{LastKey: []byte("c"), Handle: blockHandle{Offset: 1 << 40, Size: 1 << 33}},
+	}
+	decoded, err := decodeIndex(encodeIndex(entries), formatVersion)
+	require.NoError(t, err)
+	assert.Equal(t, entries, decoded)
+
+	f := &footer{Meta: blockHandle{Offset: 1 << 41, Size: 20}, Index: entries[2].Handle, Version: formatVersion}
+	decodedFooter, err := footerFromBytes(f.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, f, decodedFooter)
+}
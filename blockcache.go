@@ -0,0 +1,182 @@
+package sstable
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBlockCacheShards is the number of shards used by NewBlockCache.
+const DefaultBlockCacheShards = 16
+
+// minBlockCacheShardCapacity is the smallest share of the capacity a shard is given, so that small caches use
+// fewer shards rather than shards too small to hold a block.
+const minBlockCacheShardCapacity = 16 * DefaultBlockSize
+
+// BlockCache is a size bounded LRU cache of decoded blocks which can be shared by any number of DiskTables.
+// Entries are keyed by table identity and block offset, and the cache is split into shards, each with its own lock
+// and an equal share of the capacity, so concurrent readers rarely contend. A block larger than its shard's share
+// is still cached, evicting everything else unpinned in the shard, until the next insert into the shard.
+type BlockCache struct {
+	shards []*cacheShard
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// CacheStats is a snapshot of a BlockCache's usage.
+type CacheStats struct {
+	Hits     uint64
+	Misses   uint64
+	Entries  int
+	Size     int64
+	Capacity int64
+}
+
+// NewBlockCache creates a cache holding up to capacity bytes of blocks.
+func NewBlockCache(capacity int64) *BlockCache {
+	return NewBlockCacheWithShards(capacity, DefaultBlockCacheShards)
+}
+
+// NewBlockCacheWithShards creates a cache holding up to capacity bytes of blocks split across shards shards. The
+// number of shards is reduced for small capacities, so that every shard holds at least a few blocks.
+func NewBlockCacheWithShards(capacity int64, shards int) *BlockCache {
+	if maxShards := capacity / minBlockCacheShardCapacity; int64(shards) > maxShards {
+		shards = int(maxShards)
+	}
+	if shards < 1 {
+		shards = 1
+	}
+	c := &BlockCache{shards: make([]*cacheShard, shards)}
+	for i := range c.shards {
+		c.shards[i] = &cacheShard{
+			capacity: capacity / int64(shards),
+			entries:  make(map[cacheKey]*list.Element),
+			lru:      list.New(),
+		}
+	}
+	return c
+}
+
+func (c *BlockCache) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		stats.Entries += len(shard.entries)
+		stats.Size += shard.size
+		stats.Capacity += shard.capacity
+		shard.mu.Unlock()
+	}
+	return stats
+}
+
+type cacheKey struct {
+	tableID uint64
+	offset  uint64
+}
+
+func (c *BlockCache) shard(key cacheKey) *cacheShard {
+	h := (key.tableID*0x9e3779b97f4a7c15 ^ key.offset) * 0xbf58476d1ce4e5b9
+	return c.shards[(h>>32)%uint64(len(c.shards))]
+}
+
+func (c *BlockCache) get(key cacheKey) (any, bool) {
+	value, ok := c.shard(key).get(key)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, ok
+}
+
+// insert adds a value costing charge bytes to the cache. Pinned values count towards the capacity but are never
+// evicted, they stay until erased.
+func (c *BlockCache) insert(key cacheKey, value any, charge int64, pinned bool) {
+	c.shard(key).insert(key, value, charge, pinned)
+}
+
+func (c *BlockCache) erase(key cacheKey) {
+	c.shard(key).erase(key)
+}
+
+// eraseTable removes every block of the table, pinned or not.
+func (c *BlockCache) eraseTable(tableID uint64) {
+	for _, shard := range c.shards {
+		shard.eraseTable(tableID)
+	}
+}
+
+type cacheEntry struct {
+	key    cacheKey
+	value  any
+	charge int64
+	pinned bool
+}
+
+type cacheShard struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	entries  map[cacheKey]*list.Element
+	// lru holds the entries with the most recently used at the front
+	lru *list.List
+}
+
+func (s *cacheShard) get(key cacheKey) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).value, true
+}
+
+func (s *cacheShard) insert(key cacheKey, value any, charge int64, pinned bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+	entry := &cacheEntry{key: key, value: value, charge: charge, pinned: pinned}
+	s.entries[key] = s.lru.PushFront(entry)
+	s.size += charge
+
+	// the new entry is at the front and never evicted, so a block larger than the shard is still admitted
+	for elem := s.lru.Back(); elem != s.lru.Front() && s.size > s.capacity; {
+		prev := elem.Prev()
+		if !elem.Value.(*cacheEntry).pinned {
+			s.remove(elem)
+		}
+		elem = prev
+	}
+}
+
+func (s *cacheShard) erase(key cacheKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+}
+
+func (s *cacheShard) eraseTable(tableID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, elem := range s.entries {
+		if key.tableID == tableID {
+			s.remove(elem)
+		}
+	}
+}
+
+func (s *cacheShard) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	s.lru.Remove(elem)
+	delete(s.entries, entry.key)
+	s.size -= entry.charge
+}
@@ -0,0 +1,115 @@
+package sstable
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestBlockCache_Eviction(t *testing.T) {
+	cache := NewBlockCacheWithShards(100, 1)
+
+	cache.insert(cacheKey{tableID: 1, offset: 0}, "pinned", 40, true)
+	cache.insert(cacheKey{tableID: 1, offset: 1}, "first", 30, false)
+	cache.insert(cacheKey{tableID: 1, offset: 2}, "second", 30, false)
+
+	value, ok := cache.get(cacheKey{tableID: 1, offset: 1})
+	require.True(t, ok)
+	assert.Equal(t, "first", value)
+
+	// the second entry is now least recently used, and the pinned entry can never be evicted
+	cache.insert(cacheKey{tableID: 2, offset: 0}, "third", 30, false)
+	_, ok = cache.get(cacheKey{tableID: 1, offset: 2})
+	assert.False(t, ok)
+	_, ok = cache.get(cacheKey{tableID: 1, offset: 0})
+	assert.True(t, ok)
+	_, ok = cache.get(cacheKey{tableID: 1, offset: 1})
+	assert.True(t, ok)
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(3), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 3, stats.Entries)
+	assert.Equal(t, int64(100), stats.Size)
+
+	cache.erase(cacheKey{tableID: 1, offset: 0})
+	assert.Equal(t, int64(60), cache.Stats().Size)
+}
+
+func TestDiskTable_BlockCache(t *testing.T) {
+	cache := NewBlockCache(1 << 20)
+
+	var tables []*DiskTable
+	for tableIdx, pin := range []bool{true, false} {
+		testTableName := fmt.Sprintf("TestDiskTableBlockCache%d", tableIdx)
+		var allRecords []*Record
+		for i := 0; i < 500; i++ {
+			key := []byte(fmt.Sprintf("key-%04d", i))
+			allRecords = append(allRecords, NewRecordWithCount(key, key, uint64(i)))
+		}
+		require.NoError(t, NewSSTable("ExampleTest", allRecords).SaveToDisk(func(tableName string) string {
+			return testTableName
+		}))
+		defer os.Remove(testTableName)
+
+		diskTable, err := NewDiskTableWithOptions(testTableName, &ReadOptions{BlockCache: cache, PinIndexAndFilter: pin})
+		require.NoError(t, err)
+		tables = append(tables, diskTable)
+	}
+	pinnedEntries := cache.Stats().Entries
+	assert.Equal(t, 2, pinnedEntries)
+
+	for _, diskTable := range tables {
+		for i := 0; i < 3; i++ {
+			rec, err := diskTable.Get([]byte("key-0042"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("key-0042"), rec.Value)
+		}
+	}
+
+	stats := cache.Stats()
+	// each table misses its data block once, and the unpinned table also misses its filter and index once
+	assert.Equal(t, uint64(4), stats.Misses)
+	assert.Equal(t, uint64(8), stats.Hits)
+	assert.Equal(t, pinnedEntries+4, stats.Entries)
+
+	for _, diskTable := range tables {
+		require.NoError(t, diskTable.Close())
+	}
+	// closing a table releases its cached blocks as well as its pinned ones
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestBlockCache_SmallCapacity(t *testing.T) {
+	cache := NewBlockCache(2 * minBlockCacheShardCapacity)
+	assert.Len(t, cache.shards, 2)
+	assert.Equal(t, int64(2*minBlockCacheShardCapacity), cache.Stats().Capacity)
+
+	cache = NewBlockCache(DefaultBlockSize)
+	require.Len(t, cache.shards, 1)
+	cache.insert(cacheKey{tableID: 1, offset: 0}, "block", DefaultBlockSize, false)
+	_, ok := cache.get(cacheKey{tableID: 1, offset: 0})
+	assert.True(t, ok)
+}
+
+func TestBlockCache_OversizedBlock(t *testing.T) {
+	cache := NewBlockCacheWithShards(100, 1)
+	cache.insert(cacheKey{tableID: 1, offset: 0}, "pinned", 40, true)
+	cache.insert(cacheKey{tableID: 1, offset: 1}, "small", 30, false)
+
+	// a block larger than the cache evicts everything unpinned but is itself admitted
+	cache.insert(cacheKey{tableID: 1, offset: 2}, "large", 150, false)
+	_, ok := cache.get(cacheKey{tableID: 1, offset: 1})
+	assert.False(t, ok)
+	value, ok := cache.get(cacheKey{tableID: 1, offset: 2})
+	require.True(t, ok)
+	assert.Equal(t, "large", value)
+
+	// and is evicted in turn by the next insert
+	cache.insert(cacheKey{tableID: 1, offset: 3}, "small", 30, false)
+	_, ok = cache.get(cacheKey{tableID: 1, offset: 2})
+	assert.False(t, ok)
+	assert.Equal(t, int64(70), cache.Stats().Size)
+}
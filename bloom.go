@@ -0,0 +1,82 @@
+package sstable
+
+import "hash/fnv"
+
+// maxBloomProbes caps the number of hash probes per key, beyond which lookups get slower for little gain.
+const maxBloomProbes = 30
+
+// bloomFilter is a Bloom filter over the keys of a table, stored as its bit array followed by a single byte
+// holding the number of probes used per key. Probes are derived from a single 64-bit hash by double hashing, so
+// filters of more than 2^32 bits spread their probes over every bit.
+type bloomFilter []byte
+
+func bloomHash(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	return h.Sum64()
+}
+
+// bloomFilterBits returns the size in bits of a filter over keys keys with bitsPerKey bits per key, rounded up to
+// whole bytes.
+func bloomFilterBits(keys, bitsPerKey int) uint64 {
+	bits := uint64(keys) * uint64(bitsPerKey)
+	if bits < 64 {
+		bits = 64
+	}
+	return (bits + 7) / 8 * 8
+}
+
+// newBloomFilter builds a filter from the hashes of every key with bitsPerKey bits of filter per key. Around ten
+// bits per key gives a false positive rate of roughly one percent.
+func newBloomFilter(hashes []uint64, bitsPerKey int) bloomFilter {
+	// ln(2) * bitsPerKey probes minimises the false positive rate
+	probes := bitsPerKey * 69 / 100
+	if probes < 1 {
+		probes = 1
+	}
+	if probes > maxBloomProbes {
+		probes = maxBloomProbes
+	}
+
+	bits := bloomFilterBits(len(hashes), bitsPerKey)
+	filter := make(bloomFilter, bits/8+1)
+	for _, h := range hashes {
+		delta := h>>33 | h<<31
+		for i := 0; i < probes; i++ {
+			bit := h % bits
+			filter[bit/8] |= 1 << (bit % 8)
+			h += delta
+		}
+	}
+	filter[len(filter)-1] = byte(probes)
+	return filter
+}
+
+// probes returns the number of probes per key, or false if the filter can not rule any key out.
+func (f bloomFilter) probes() (int, bool) {
+	if len(f) < 2 {
+		return 0, false
+	}
+	probes := int(f[len(f)-1])
+	// larger counts are reserved for filter encodings this version does not understand
+	return probes, probes <= maxBloomProbes
+}
+
+// mayContain reports whether the key may have been added to the filter. A false result is definitive.
+func (f bloomFilter) mayContain(key []byte) bool {
+	probes, ok := f.probes()
+	if !ok {
+		return true
+	}
+	bits := uint64(len(f)-1) * 8
+	h := bloomHash(key)
+	delta := h>>33 | h<<31
+	for i := 0; i < probes; i++ {
+		bit := h % bits
+		if f[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+		h += delta
+	}
+	return true
+}
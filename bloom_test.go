@@ -0,0 +1,88 @@
+package sstable
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestBloomFilter_FalsePositiveRate(t *testing.T) {
+	var hashes []uint64
+	for i := 0; i < 10000; i++ {
+		hashes = append(hashes, bloomHash([]byte(fmt.Sprintf("key-%d", i))))
+	}
+	filter := newBloomFilter(hashes, DefaultBloomBitsPerKey)
+
+	for i := 0; i < 10000; i++ {
+		require.True(t, filter.mayContain([]byte(fmt.Sprintf("key-%d", i))))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.mayContain([]byte(fmt.Sprintf("missing-%d", i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestBloomFilterBits_Oversized(t *testing.T) {
+	// a bit count which is a multiple of 2^32 used to truncate to zero as a uint32, leaving nothing to probe modulo
+	assert.Equal(t, uint64(1)<<32, bloomFilterBits(1<<29, 8))
+
+	bits := bloomFilterBits(429496730, DefaultBloomBitsPerKey)
+	assert.Greater(t, bits, uint64(math.MaxUint32))
+	assert.Zero(t, bits%8)
+}
+
+func TestDiskTable_BloomFilter(t *testing.T) {
+	testTableName := "TestDiskTableBloomFilter"
+
+	var allRecords []*Record
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", i))
+		allRecords = append(allRecords, NewRecordWithCount(key, key, uint64(i)))
+	}
+	allRecords = append(allRecords, NewTombstoneWithCount([]byte("key-deleted"), 200))
+
+	table := NewSSTable("ExampleTest", allRecords)
+	require.NoError(t, table.SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+	defer os.Remove(testTableName)
+
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+	defer diskTable.Close()
+	require.NotNil(t, diskTable.filter)
+
+	for _, rec := range allRecords {
+		assert.True(t, diskTable.filter.mayContain(rec.Key))
+	}
+	found, err := diskTable.Contains([]byte("key-0042"))
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = diskTable.Contains([]byte("key-deleted"))
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	opts := DefaultWriteOptions()
+	opts.BloomBitsPerKey = 0
+	noFilter := NewSSTableWithOptions("ExampleTest", allRecords, opts)
+	require.NoError(t, noFilter.SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+
+	unfiltered, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+	defer unfiltered.Close()
+	assert.Nil(t, unfiltered.filter)
+
+	found, err = unfiltered.Contains([]byte("key-0042"))
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
@@ -1,63 +1,154 @@
 package sstable
 
-import "bytes"
+import (
+	"bytes"
+	"unsafe"
+)
 
-// TODO - Implment RedBlack tree which would have better worst case complexity
+// Bst is a memtable held in an unbalanced binary search tree. Keys inserted in sorted order leave it as deep as it
+// has keys, so RedBlackTree should be preferred where keys arrive in order.
 type Bst struct {
-	Root *BstNode
+	Root           *BstNode
+	count          int
+	size           int64
+	flushThreshold int64
 }
 
+// bstNodeOverhead is the memory a node takes up besides its key and value.
+const bstNodeOverhead = int64(unsafe.Sizeof(BstNode{}))
+
 func NewBst() *Bst {
-	return &Bst{}
+	return NewBstWithOptions(DefaultMemtableOptions())
+}
+
+// NewBstWithOptions creates an empty tree which signals a flush according to opts. A nil opts is the same as
+// DefaultMemtableOptions.
+func NewBstWithOptions(opts *MemtableOptions) *Bst {
+	if opts == nil {
+		opts = DefaultMemtableOptions()
+	}
+	return &Bst{flushThreshold: opts.FlushThreshold}
 }
 
 func (b *Bst) Insert(key, value []byte, atomicCount uint64) {
-	node := &BstNode{
+	b.insert(&BstNode{
 		Key:         key,
 		Value:       value,
 		AtomicCount: atomicCount,
+	})
+}
+
+func (b *Bst) Delete(key []byte, atomicCount uint64) {
+	b.insert(&BstNode{
+		Kind:        KindDelete,
+		Key:         key,
+		AtomicCount: atomicCount,
+	})
+}
+
+// insert adds node to the tree, or applies it to the existing node for its key, keeping the size up to date.
+func (b *Bst) insert(node *BstNode) {
+	link := &b.Root
+	for *link != nil {
+		current := *link
+		cmp := bytes.Compare(current.Key, node.Key)
+		if cmp == 0 {
+			if node.AtomicCount > current.AtomicCount {
+				b.size += int64(len(node.Value) - len(current.Value))
+				current.Kind = node.Kind
+				current.Value = node.Value
+				current.AtomicCount = node.AtomicCount
+			}
+			return
+		}
+		if cmp < 0 {
+			link = &current.Right
+		} else {
+			link = &current.Left
+		}
 	}
-	if b.Root == nil {
-		b.Root = node
-		return
-	}
-	b.Root.Insert(node)
+	*link = node
+	b.count++
+	b.size += bstNodeOverhead + int64(len(node.Key)+len(node.Value))
+}
+
+func (b *Bst) Len() int {
+	return b.count
+}
+
+func (b *Bst) ApproximateSize() int64 {
+	return b.size
+}
+
+func (b *Bst) ShouldFlush() bool {
+	return shouldFlush(b.size, b.flushThreshold)
 }
 
 func (b *Bst) Contains(key []byte) (bool, error) {
 	found := b.Root.SearchKey(key)
-	return found != nil, nil
+	return found != nil && !found.Deleted(), nil
 }
 
 func (b *Bst) Get(key []byte) (*Record, error) {
 	found := b.Root.SearchKey(key)
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	if found.Deleted() {
+		return nil, ErrDeleted
+	}
 	return found, nil
 }
 
 func (b *Bst) Scan() ([]*Record, error) {
 	var results []*Record
-	inOrderTraverse(b.Root, &results)
+	inOrderTraverseLimit(b.Root, &results, nil, limitValue(nil))
 	return results, nil
 }
 
 func (b *Bst) ScanWithLimit(limit *Limit) ([]*Record, error) {
 	var results []*Record
-	inOrderTraverseLimit(b.Root, &results, nil, int(limit.MaxResults))
+	inOrderTraverseLimit(b.Root, &results, nil, limitValue(limit))
 	return results, nil
 }
 
 func (b *Bst) ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
 	var results []*Record
-	inOrderTraverseLimit(b.Root, &results, pred, int(limit.MaxResults))
+	inOrderTraverseLimit(b.Root, &results, pred, limitValue(limit))
 	return results, nil
 }
 
+// NewIterator returns an iterator over the tree. Nodes have no parent links, so each step searches down from the
+// root for the neighbouring key rather than keeping a stack, keeping the iterator's memory constant. The tree must
+// not be modified while the iterator is in use.
+func (b *Bst) NewIterator() Iterator {
+	return &bstIterator{tree: b}
+}
+
+func (b *Bst) ReverseScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(b.NewIterator(), nil, nil, limit, true)
+}
+
+func (b *Bst) ReverseScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(b.NewIterator(), nil, pred, limit, true)
+}
+
+func (b *Bst) ScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(b.NewIterator(), r, nil, limit, false)
+}
+
+func (b *Bst) ReverseScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(b.NewIterator(), r, nil, limit, true)
+}
+
 func (b *Bst) ToSSTable(tableName string) *SSTable {
-	records, _ := b.Scan()
+	var records []*Record
+	inOrderTraverse(b.Root, &records)
 	return NewSSTable(tableName, records)
 }
 
 type BstNode struct {
+	Kind        RecordKind
 	Key         []byte
 	Value       []byte
 	AtomicCount uint64
@@ -65,16 +156,21 @@ type BstNode struct {
 	Right       *BstNode
 }
 
+// Insert adds node to the subtree rooted at b, keeping only the write with the highest atomic count for a key.
+//
+// Deprecated: nodes inserted this way are not counted in the Len and ApproximateSize of the Bst holding them, so
+// the tree may never signal a flush. Use Bst.Insert or Bst.Delete instead.
 func (b *BstNode) Insert(node *BstNode) {
 
 	cmp := bytes.Compare(b.Key, node.Key)
 	if cmp == 0 {
 		if node.AtomicCount > b.AtomicCount {
+			b.Kind = node.Kind
 			b.Key = node.Key
 			b.Value = node.Value
 			b.AtomicCount = node.AtomicCount
-			return
 		}
+		return
 	}
 	if cmp < 0 {
 		if b.Right == nil {
@@ -98,7 +194,7 @@ func (b *BstNode) SearchKey(key []byte) *Record {
 
 	cmp := bytes.Compare(b.Key, key)
 	if cmp == 0 {
-		return NewRecordWithCount(b.Key, b.Value, b.AtomicCount)
+		return b.record()
 	}
 	if cmp < 0 {
 		return b.Right.SearchKey(key)
@@ -107,12 +203,19 @@ func (b *BstNode) SearchKey(key []byte) *Record {
 	}
 }
 
+func (b *BstNode) record() *Record {
+	rec := NewRecordWithCount(b.Key, b.Value, b.AtomicCount)
+	rec.Kind = b.Kind
+	return rec
+}
+
+// inOrderTraverse collects every record in the tree, including tombstones.
 func inOrderTraverse(node *BstNode, results *[]*Record) {
 	if node == nil {
 		return
 	}
 	inOrderTraverse(node.Left, results)
-	*results = append(*results, NewRecordWithCount(node.Key, node.Value, node.AtomicCount))
+	*results = append(*results, node.record())
 	inOrderTraverse(node.Right, results)
 }
 
@@ -120,12 +223,112 @@ func inOrderTraverseLimit(node *BstNode, results *[]*Record, pred Predicate, lim
 	if node == nil || len(*results) == limit {
 		return
 	}
-	inOrderTraverseLimit(node, results, pred, limit)
+	inOrderTraverseLimit(node.Left, results, pred, limit)
 	if len(*results) == limit {
 		return
 	}
-	if pred == nil || pred(node.Key, node.Value) {
-		*results = append(*results, NewRecordWithCount(node.Key, node.Value, node.AtomicCount))
+	if node.Kind != KindDelete && (pred == nil || pred(node.Key, node.Value)) {
+		*results = append(*results, node.record())
 	}
-	inOrderTraverseLimit(node, results, pred, limit)
+	inOrderTraverseLimit(node.Right, results, pred, limit)
+}
+
+type bstIterator struct {
+	tree    *Bst
+	current *BstNode
+}
+
+// seek positions the iterator on the closest node to key in the given direction, with inclusive deciding whether
+// a node with an equal key qualifies.
+func (it *bstIterator) seek(key []byte, forward, inclusive bool) bool {
+	var candidate *BstNode
+	node := it.tree.Root
+	for node != nil {
+		cmp := bytes.Compare(node.Key, key)
+		if cmp == 0 && inclusive {
+			candidate = node
+			break
+		}
+		if (forward && cmp > 0) || (!forward && cmp < 0) {
+			candidate = node
+		}
+		if cmp > 0 || (cmp == 0 && !forward) {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	it.current = candidate
+	return it.current != nil
+}
+
+// skipDeleted moves past any tombstones in the given direction.
+func (it *bstIterator) skipDeleted(forward bool) bool {
+	for it.current != nil && it.current.Kind == KindDelete {
+		it.seek(it.current.Key, forward, false)
+	}
+	return it.current != nil
+}
+
+func (it *bstIterator) SeekGE(key []byte) bool {
+	it.seek(key, true, true)
+	return it.skipDeleted(true)
+}
+
+func (it *bstIterator) SeekLT(key []byte) bool {
+	it.seek(key, false, false)
+	return it.skipDeleted(false)
+}
+
+func (it *bstIterator) First() bool {
+	it.current = it.tree.Root
+	for it.current != nil && it.current.Left != nil {
+		it.current = it.current.Left
+	}
+	return it.skipDeleted(true)
+}
+
+func (it *bstIterator) Last() bool {
+	it.current = it.tree.Root
+	for it.current != nil && it.current.Right != nil {
+		it.current = it.current.Right
+	}
+	return it.skipDeleted(false)
+}
+
+func (it *bstIterator) Next() bool {
+	if it.current == nil {
+		return false
+	}
+	it.seek(it.current.Key, true, false)
+	return it.skipDeleted(true)
+}
+
+func (it *bstIterator) Prev() bool {
+	if it.current == nil {
+		return false
+	}
+	it.seek(it.current.Key, false, false)
+	return it.skipDeleted(false)
+}
+
+func (it *bstIterator) Key() []byte {
+	return it.current.Key
+}
+
+func (it *bstIterator) Value() []byte {
+	return it.current.Value
+}
+
+func (it *bstIterator) Record() *Record {
+	return NewRecordWithCount(it.current.Key, it.current.Value, it.current.AtomicCount)
+}
+
+func (it *bstIterator) Error() error {
+	return nil
+}
+
+func (it *bstIterator) Close() error {
+	it.current = nil
+	return nil
 }
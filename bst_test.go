@@ -29,3 +29,45 @@ func TestBst_Contains(t *testing.T) {
 	ok, _ = bst.Contains([]byte("Not found"))
 	assert.False(t, ok)
 }
+
+func TestBst_InsertOlderCountKeepsNewest(t *testing.T) {
+	bst := NewBst()
+	bst.Insert([]byte("b"), []byte("new"), 2)
+	bst.Insert([]byte("b"), []byte("old"), 1)
+	bst.Insert([]byte("a"), []byte("value"), 3)
+
+	all, _ := bst.Scan()
+	assert.Len(t, all, 2)
+	assert.Equal(t, []byte("a"), all[0].Key)
+	assert.Equal(t, []byte("b"), all[1].Key)
+	assert.Equal(t, []byte("new"), all[1].Value)
+	assert.Equal(t, uint64(2), all[1].AtomicCount)
+}
+
+func TestBstNode_Insert(t *testing.T) {
+	root := &BstNode{Key: []byte("b"), Value: []byte("new"), AtomicCount: 2}
+	root.Insert(&BstNode{Key: []byte("b"), Value: []byte("old"), AtomicCount: 1})
+	root.Insert(&BstNode{Key: []byte("a"), Value: []byte("value"), AtomicCount: 3})
+
+	assert.Equal(t, []byte("new"), root.SearchKey([]byte("b")).Value)
+	assert.Equal(t, []byte("value"), root.SearchKey([]byte("a")).Value)
+	assert.Equal(t, []byte("a"), root.Left.Key)
+}
+
+func TestBst_ScanWithLimit(t *testing.T) {
+	bst := NewBst()
+	for i, key := range []string{"d", "b", "f", "a", "c", "e", "g"} {
+		bst.Insert([]byte(key), []byte(key), uint64(i+1))
+	}
+
+	limited, _ := bst.ScanWithLimit(&Limit{MaxResults: 3})
+	assert.Equal(t, []string{"a", "b", "c"}, recordKeys(limited))
+
+	all, _ := bst.ScanWithLimit(&Limit{MaxResults: 10})
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f", "g"}, recordKeys(all))
+
+	filtered, _ := bst.ScanWithPredicate(func(key, value []byte) bool {
+		return key[0] > 'b'
+	}, &Limit{MaxResults: 2})
+	assert.Equal(t, []string{"c", "d"}, recordKeys(filtered))
+}
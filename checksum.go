@@ -0,0 +1,27 @@
+package sstable
+
+import "hash/crc32"
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksum returns the CRC32C of the contents, which is what every checksummed region of a table is stored with.
+func checksum(contents []byte) uint32 {
+	return crc32.Checksum(contents, castagnoliTable)
+}
+
+// appendChecksum appends the checksum of contents to it, producing the trailer every block is stored with.
+func appendChecksum(contents []byte) []byte {
+	return byteOrdering.AppendUint32(contents, checksum(contents))
+}
+
+// stripChecksum verifies the trailing checksum written by appendChecksum and returns the contents without it.
+func stripChecksum(contents []byte) ([]byte, error) {
+	if len(contents) < 4 {
+		return nil, newCorruptionError(0, "block is too short to hold a checksum")
+	}
+	payload := contents[:len(contents)-4]
+	if byteOrdering.Uint32(contents[len(payload):]) != checksum(payload) {
+		return nil, newCorruptionError(int64(len(payload)), "block checksum mismatch")
+	}
+	return payload, nil
+}
@@ -0,0 +1,140 @@
+package sstable
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// CompressionType identifies the codec a block was written with. It is stored alongside every block so tables
+// written with different codecs can be read without any configuration.
+type CompressionType uint8
+
+const (
+	NoCompression CompressionType = iota
+	FlateCompression
+	ZlibCompression
+)
+
+var ErrUnknownCompression = errors.New("sstable: unknown compression type")
+
+// Codec compresses data blocks as they are written and decompresses them when they are read back.
+type Codec interface {
+	Type() CompressionType
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[CompressionType]Codec{
+		NoCompression:    NoneCodec{},
+		FlateCompression: FlateCodec{},
+		ZlibCompression:  ZlibCodec{},
+	}
+)
+
+// RegisterCodec makes a codec available for reading blocks written with its type, replacing any codec already
+// registered for that type.
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[codec.Type()] = codec
+}
+
+func codecFor(compressionType CompressionType) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[compressionType]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnknownCompression, compressionType)
+	}
+	return codec, nil
+}
+
+// NoneCodec stores blocks as they are.
+type NoneCodec struct{}
+
+func (NoneCodec) Type() CompressionType {
+	return NoCompression
+}
+
+func (NoneCodec) Compress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+func (NoneCodec) Decompress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+// FlateCodec compresses blocks with compress/flate. A zero Level uses flate.DefaultCompression.
+type FlateCodec struct {
+	Level int
+}
+
+func (FlateCodec) Type() CompressionType {
+	return FlateCompression
+}
+
+func (f FlateCodec) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, compressionLevel(f.Level, flate.DefaultCompression))
+	if err != nil {
+		return nil, err
+	}
+	return finishCompression(&buf, w, src)
+}
+
+func (FlateCodec) Decompress(src []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(src))
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// ZlibCodec compresses blocks with compress/zlib. A zero Level uses zlib.DefaultCompression.
+type ZlibCodec struct {
+	Level int
+}
+
+func (ZlibCodec) Type() CompressionType {
+	return ZlibCompression
+}
+
+func (z ZlibCodec) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, compressionLevel(z.Level, zlib.DefaultCompression))
+	if err != nil {
+		return nil, err
+	}
+	return finishCompression(&buf, w, src)
+}
+
+func (ZlibCodec) Decompress(src []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func compressionLevel(level, defaultLevel int) int {
+	if level == 0 {
+		return defaultLevel
+	}
+	return level
+}
+
+func finishCompression(buf *bytes.Buffer, w io.WriteCloser, src []byte) ([]byte, error) {
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestCompression_RoundTrip(t *testing.T) {
+	testTableName := "TestCompressionRoundTrip"
+
+	var allRecords []*Record
+	for i := 0; i < 200; i++ {
+		value := bytes.Repeat([]byte(fmt.Sprintf("value-%d ", i)), 20)
+		allRecords = append(allRecords, NewRecordWithCount([]byte(fmt.Sprintf("key-%04d", i)), value, uint64(i)))
+	}
+	uncompressed, err := NewSSTable("ExampleTest", allRecords).ToBytes()
+	require.NoError(t, err)
+
+	for _, codec := range []Codec{NoneCodec{}, FlateCodec{}, ZlibCodec{Level: 9}} {
+		t.Run(fmt.Sprintf("codec %d", codec.Type()), func(t *testing.T) {
+			opts := DefaultWriteOptions()
+			opts.Compression = codec
+			table := NewSSTableWithOptions("ExampleTest", allRecords, opts)
+			contents, err := table.ToBytes()
+			require.NoError(t, err)
+			if codec.Type() != NoCompression {
+				assert.Less(t, len(contents), len(uncompressed)/2)
+			}
+
+			require.NoError(t, os.WriteFile(testTableName, contents, 0644))
+			defer os.Remove(testTableName)
+
+			diskTable, err := NewDiskTable(testTableName)
+			require.NoError(t, err)
+
+			rec, err := diskTable.Get([]byte("key-0123"))
+			require.NoError(t, err)
+			assert.Equal(t, allRecords[123].Value, rec.Value)
+
+			results, err := diskTable.Scan()
+			require.NoError(t, err)
+			assert.Equal(t, allRecords, results)
+		})
+	}
+}
+
+func TestUnframeBlock_UnknownCompression(t *testing.T) {
+	contents := appendChecksum([]byte{1, 2, 3, 200})
+	_, err := unframeBlock(contents)
+	assert.ErrorIs(t, err, ErrUnknownCompression)
+}
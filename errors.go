@@ -0,0 +1,54 @@
+package sstable
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned by Get when a key is not present, or has been deleted.
+	ErrNotFound = errors.New("sstable: key not found")
+	// ErrDeleted is returned by Get when the newest write of a key is a tombstone. It matches ErrNotFound, and lets
+	// a caller reading several tables from newest to oldest stop at a deletion rather than consult older tables.
+	ErrDeleted = fmt.Errorf("%w: key deleted", ErrNotFound)
+	// ErrCorrupt matches every CorruptionError, for callers which only need to know a table is damaged.
+	ErrCorrupt = errors.New("sstable: corrupt data")
+	// ErrClosed is returned by operations on a DiskTable or TableCache which has been closed.
+	ErrClosed          = errors.New("sstable: table is closed")
+	ErrMmapUnsupported = errors.New("sstable: memory mapping is not supported on this platform")
+)
+
+// CorruptionError reports that bytes read back from a table failed validation, such as a checksum mismatch or a
+// length running past the end of the data it was read from.
+type CorruptionError struct {
+	File   string
+	Offset int64
+	Reason string
+}
+
+func (e *CorruptionError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("sstable: corruption at offset %d: %s", e.Offset, e.Reason)
+	}
+	return fmt.Sprintf("sstable: corruption in %s at offset %d: %s", e.File, e.Offset, e.Reason)
+}
+
+// Is reports every CorruptionError as matching ErrCorrupt.
+func (e *CorruptionError) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+func newCorruptionError(offset int64, reason string) *CorruptionError {
+	return &CorruptionError{Offset: offset, Reason: reason}
+}
+
+// annotateCorruption fills in the file name and absolute offset of a CorruptionError raised while decoding a region
+// which started at base within the file. Other errors are returned unchanged.
+func annotateCorruption(err error, file string, base int64) error {
+	var corrupt *CorruptionError
+	if errors.As(err, &corrupt) {
+		corrupt.File = file
+		corrupt.Offset += base
+	}
+	return err
+}
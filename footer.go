@@ -0,0 +1,95 @@
+package sstable
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// tableMagic ends every table file, allowing readers to reject files which are not tables.
+	tableMagic uint64 = 0x88e241b785f4cff7
+	// formatVersion is the layout version written by this package. Tables written with any earlier version are
+	// still read, with each section decoded according to the version recorded in the table's footer.
+	formatVersion uint32 = 3
+	// footerSize is the fixed size of the footer: the meta, index and filter handles, the format version, a
+	// checksum of the preceding fields and the magic number.
+	footerSize = 3*16 + 4 + 4 + 8
+	// footerSizeV1 is the size of the footer of version 1 tables, whose handles held 32-bit offsets and sizes.
+	footerSizeV1 = 3*8 + 4 + 4 + 8
+	// footerVersionOffset is the distance of the format version from the end of the footer, which is the same for
+	// every version so it can be read before the footer's size is known.
+	footerVersionOffset = 4 + 4 + 8
+)
+
+var (
+	ErrNotTable           = errors.New("sstable: not a table file")
+	ErrUnsupportedVersion = errors.New("sstable: unsupported table format version")
+)
+
+// footer is stored in the final bytes of every table and locates the other sections of the file. A zero sized
+// handle means the section is absent.
+type footer struct {
+	Meta    blockHandle
+	Index   blockHandle
+	Filter  blockHandle
+	Version uint32
+}
+
+func (f *footer) ToBytes() []byte {
+	contents := make([]byte, 0, footerSize)
+	for _, handle := range []blockHandle{f.Meta, f.Index, f.Filter} {
+		contents = byteOrdering.AppendUint64(contents, handle.Offset)
+		contents = byteOrdering.AppendUint64(contents, handle.Size)
+	}
+	contents = byteOrdering.AppendUint32(contents, f.Version)
+	contents = appendChecksum(contents)
+	return byteOrdering.AppendUint64(contents, tableMagic)
+}
+
+// footerFromBytes decodes a footer from the final bytes of a table, which must hold at least the footer, with any
+// corruption reported at its offset within tail. The magic number is checked before anything else so foreign files
+// are reported as such rather than as corrupt tables, and the format version then determines the size and layout
+// of the rest of the footer.
+func footerFromBytes(tail []byte) (*footer, error) {
+	if len(tail) < footerSizeV1 || byteOrdering.Uint64(tail[len(tail)-8:]) != tableMagic {
+		return nil, ErrNotTable
+	}
+	version := byteOrdering.Uint32(tail[len(tail)-footerVersionOffset:])
+	if !supportedVersion(version) {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, version)
+	}
+	size := footerSizeFor(version)
+	if len(tail) < size {
+		return nil, ErrNotTable
+	}
+	contents := tail[len(tail)-size:]
+	payload, err := stripChecksum(contents[:size-8])
+	if err != nil {
+		return nil, annotateCorruption(err, "", int64(len(tail)-size))
+	}
+
+	f := &footer{Version: version}
+	for i, handle := range []*blockHandle{&f.Meta, &f.Index, &f.Filter} {
+		if version == 1 {
+			handle.Offset = uint64(byteOrdering.Uint32(payload[8*i:]))
+			handle.Size = uint64(byteOrdering.Uint32(payload[8*i+4:]))
+		} else {
+			handle.Offset = byteOrdering.Uint64(payload[16*i:])
+			handle.Size = byteOrdering.Uint64(payload[16*i+8:])
+		}
+	}
+	return f, nil
+}
+
+// supportedVersion reports whether tables written with version can be read.
+func supportedVersion(version uint32) bool {
+	return version >= 1 && version <= formatVersion
+}
+
+// footerSizeFor returns the size of the footer of a table written with version.
+func footerSizeFor(version uint32) int {
+	if version == 1 {
+		return footerSizeV1
+	}
+	return footerSize
+}
@@ -0,0 +1,106 @@
+package sstable
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// legacyTable lays records out in a single data block as a table of an earlier format version, which this package
+// no longer writes.
+func legacyTable(t *testing.T, version uint32, tableName string, records []*Record) []byte {
+	builder := newBlockBuilder(DefaultRestartInterval)
+	for _, rec := range records {
+		require.NoError(t, builder.add(rec))
+	}
+	contents, err := frameBlock(builder.finish(), nil)
+	require.NoError(t, err)
+	dataHandle := blockHandle{Size: uint64(len(contents))}
+	lastKey := records[len(records)-1].Key
+
+	var index, meta []byte
+	switch version {
+	case 1:
+		index = byteOrdering.AppendUint32(index, uint32(len(lastKey)))
+		index = append(index, lastKey...)
+		index = byteOrdering.AppendUint32(index, uint32(dataHandle.Offset))
+		index = byteOrdering.AppendUint32(index, uint32(dataHandle.Size))
+		meta = byteOrdering.AppendUint32(meta, uint32(len(records)))
+	case 2:
+		index = encodeIndex([]indexEntry{{LastKey: lastKey, Handle: dataHandle}})
+		meta = byteOrdering.AppendUint64(meta, uint64(len(records)))
+	default:
+		t.Fatalf("no layout for version %d", version)
+	}
+	meta = byteOrdering.AppendUint32(meta, DefaultBlockSize)
+	meta = append(meta, tableName...)
+
+	appendBlock := func(raw []byte) blockHandle {
+		framed, err := frameBlock(raw, nil)
+		require.NoError(t, err)
+		handle := blockHandle{Offset: uint64(len(contents)), Size: uint64(len(framed))}
+		contents = append(contents, framed...)
+		return handle
+	}
+	indexHandle := appendBlock(index)
+	metaHandle := appendBlock(meta)
+
+	if version > 1 {
+		return append(contents, (&footer{Meta: metaHandle, Index: indexHandle, Version: version}).ToBytes()...)
+	}
+	var footerBytes []byte
+	for _, handle := range []blockHandle{metaHandle, indexHandle, {}} {
+		footerBytes = byteOrdering.AppendUint32(footerBytes, uint32(handle.Offset))
+		footerBytes = byteOrdering.AppendUint32(footerBytes, uint32(handle.Size))
+	}
+	footerBytes = byteOrdering.AppendUint32(footerBytes, version)
+	footerBytes = appendChecksum(footerBytes)
+	footerBytes = byteOrdering.AppendUint64(footerBytes, tableMagic)
+	return append(contents, footerBytes...)
+}
+
+func TestDiskTable_ReadsEarlierVersions(t *testing.T) {
+	records := []*Record{
+		NewRecordWithCount([]byte("A"), []byte("Alpha"), 1),
+		NewRecordWithCount([]byte("B"), []byte("Bravo"), 2),
+		NewTombstoneWithCount([]byte("C"), 3),
+	}
+
+	for _, version := range []uint32{1, 2} {
+		contents := legacyTable(t, version, "LegacyTest", records)
+		diskTable, err := NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), "legacy",
+			DefaultReadOptions())
+		require.NoError(t, err, "version %d", version)
+
+		assert.Equal(t, version, diskTable.footer.Version)
+		assert.Equal(t, uint64(len(records)), diskTable.TableMeta.KeyCount)
+		assert.Equal(t, []byte("LegacyTest"), diskTable.TableMeta.TableName)
+
+		rec, err := diskTable.Get([]byte("B"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("Bravo"), rec.Value)
+		_, err = diskTable.Get([]byte("C"))
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		all, err := diskTable.Scan()
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, []byte("A"), all[0].Key)
+		assert.Equal(t, []byte("B"), all[1].Key)
+		require.NoError(t, diskTable.Close())
+	}
+}
+
+func TestFooterFromBytes_Truncated(t *testing.T) {
+	f := &footer{Meta: blockHandle{Offset: 10, Size: 20}, Version: formatVersion}
+	contents := f.ToBytes()
+
+	_, err := footerFromBytes(contents[len(contents)-footerSizeV1:])
+	assert.ErrorIs(t, err, ErrNotTable)
+
+	contents[0] ^= 0x01
+	_, err = footerFromBytes(contents)
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
+}
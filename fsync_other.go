@@ -0,0 +1,9 @@
+//go:build !unix
+
+package sstable
+
+// syncDir is a no-op on platforms which do not support syncing a directory, where renames are made durable by
+// the file system itself.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package sstable
+
+import "os"
+
+// syncDir syncs a directory so that files renamed into it survive a crash. An empty dir is the working directory.
+func syncDir(dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
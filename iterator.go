@@ -0,0 +1,117 @@
+package sstable
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Iterator walks the live records of a table or memtable in key order, skipping deleted records. Positioning
+// methods report whether the iterator was left on a record, and Key, Value and Record are only valid while it is.
+// An iterator only needs memory for its current position, so it can stream tables of any size.
+type Iterator interface {
+	// SeekGE moves to the first record with a key greater than or equal to key.
+	SeekGE(key []byte) bool
+	// SeekLT moves to the last record with a key less than key.
+	SeekLT(key []byte) bool
+	First() bool
+	Last() bool
+	Next() bool
+	Prev() bool
+	Key() []byte
+	Value() []byte
+	// Record returns the current record, including its atomic count.
+	Record() *Record
+	// Error returns any error which stopped the iterator, positioning methods return false once one occurs.
+	Error() error
+	Close() error
+}
+
+// sliceIterator iterates over records which are already sorted in memory.
+type sliceIterator struct {
+	records []*Record
+	idx     int
+}
+
+func newSliceIterator(records []*Record) *sliceIterator {
+	return &sliceIterator{records: records, idx: -1}
+}
+
+func (s *sliceIterator) valid() bool {
+	return s.idx >= 0 && s.idx < len(s.records)
+}
+
+func (s *sliceIterator) skipForward() bool {
+	for s.idx < len(s.records) && s.records[s.idx].Deleted() {
+		s.idx++
+	}
+	return s.valid()
+}
+
+func (s *sliceIterator) skipBackward() bool {
+	for s.idx >= 0 && s.records[s.idx].Deleted() {
+		s.idx--
+	}
+	return s.valid()
+}
+
+func (s *sliceIterator) SeekGE(key []byte) bool {
+	s.idx = searchRecords(s.records, key)
+	return s.skipForward()
+}
+
+func (s *sliceIterator) SeekLT(key []byte) bool {
+	s.idx = searchRecords(s.records, key) - 1
+	return s.skipBackward()
+}
+
+func (s *sliceIterator) First() bool {
+	s.idx = 0
+	return s.skipForward()
+}
+
+func (s *sliceIterator) Last() bool {
+	s.idx = len(s.records) - 1
+	return s.skipBackward()
+}
+
+func (s *sliceIterator) Next() bool {
+	if s.idx < len(s.records) {
+		s.idx++
+	}
+	return s.skipForward()
+}
+
+func (s *sliceIterator) Prev() bool {
+	if s.idx >= 0 {
+		s.idx--
+	}
+	return s.skipBackward()
+}
+
+func (s *sliceIterator) Key() []byte {
+	return s.records[s.idx].Key
+}
+
+func (s *sliceIterator) Value() []byte {
+	return s.records[s.idx].Value
+}
+
+func (s *sliceIterator) Record() *Record {
+	return s.records[s.idx]
+}
+
+func (s *sliceIterator) Error() error {
+	return nil
+}
+
+func (s *sliceIterator) Close() error {
+	s.records = nil
+	return nil
+}
+
+// searchRecords returns the index of the first record with a key greater than or equal to key.
+func searchRecords(records []*Record, key []byte) int {
+	return sort.Search(len(records), func(i int) bool {
+		return bytes.Compare(records[i].Key, key) >= 0
+	})
+}
@@ -0,0 +1,124 @@
+package sstable
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+// iteratorTestSearchers builds each memtable and table type holding keys key-0000, key-0002, ... key-0198. They
+// also hold tombstones for the odd keys, which iterators must skip. A memory mapped table is only included on
+// platforms which support memory mapping.
+func iteratorTestSearchers(t *testing.T) map[string]Searcher {
+	testTableName := "TestIterator"
+
+	searchers := make(map[string]Searcher)
+	var memtables []Memtable
+	for _, tt := range memtableTests {
+		memtable := tt.newMemtable(DefaultMemtableOptions())
+		memtables = append(memtables, memtable)
+		searchers[tt.name] = memtable
+	}
+	var records []*Record
+	for i := 0; i < 200; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", i))
+		for _, memtable := range memtables {
+			memtable.Insert(key, key, uint64(i))
+		}
+		if i%2 == 0 {
+			records = append(records, NewRecordWithCount(key, key, uint64(i)))
+		} else {
+			for _, memtable := range memtables {
+				memtable.Delete(key, uint64(i)+200)
+			}
+			records = append(records, NewTombstoneWithCount(key, uint64(i)+200))
+		}
+	}
+
+	opts := DefaultWriteOptions()
+	opts.BlockSize = 128
+	table := NewSSTableWithOptions("ExampleTest", records, opts)
+	require.NoError(t, table.SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+	t.Cleanup(func() {
+		os.Remove(testTableName)
+	})
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		diskTable.Close()
+	})
+	contents, err := table.ToBytes()
+	require.NoError(t, err)
+	readerTable, err := NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), testTableName, DefaultReadOptions())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		readerTable.Close()
+	})
+
+	searchers["sstable"] = table
+	searchers["diskTable"] = diskTable
+	searchers["readerTable"] = readerTable
+	mmapTable, err := NewDiskTableWithOptions(testTableName, &ReadOptions{UseMmap: true})
+	if !errors.Is(err, ErrMmapUnsupported) {
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			mmapTable.Close()
+		})
+		searchers["mmapTable"] = mmapTable
+	}
+	return searchers
+}
+
+func TestIterator(t *testing.T) {
+	for name, searcher := range iteratorTestSearchers(t) {
+		t.Run(name, func(t *testing.T) {
+			it := searcher.NewIterator()
+			defer it.Close()
+
+			var forward []string
+			for ok := it.First(); ok; ok = it.Next() {
+				forward = append(forward, string(it.Key()))
+				assert.Equal(t, it.Key(), it.Value())
+			}
+			require.NoError(t, it.Error())
+			require.Len(t, forward, 100)
+			assert.Equal(t, "key-0000", forward[0])
+			assert.Equal(t, "key-0198", forward[99])
+
+			var backward []string
+			for ok := it.Last(); ok; ok = it.Prev() {
+				backward = append(backward, string(it.Key()))
+			}
+			require.NoError(t, it.Error())
+			require.Len(t, backward, 100)
+			for i := range forward {
+				assert.Equal(t, forward[i], backward[len(backward)-1-i])
+			}
+
+			require.True(t, it.SeekGE([]byte("key-0100")))
+			assert.Equal(t, "key-0100", string(it.Key()))
+			assert.Equal(t, uint64(100), it.Record().AtomicCount)
+			require.True(t, it.SeekGE([]byte("key-0101")))
+			assert.Equal(t, "key-0102", string(it.Key()))
+			require.True(t, it.Prev())
+			assert.Equal(t, "key-0100", string(it.Key()))
+			assert.False(t, it.SeekGE([]byte("key-0199")))
+
+			require.True(t, it.SeekLT([]byte("key-0100")))
+			assert.Equal(t, "key-0098", string(it.Key()))
+			require.True(t, it.SeekLT([]byte("key-0101")))
+			assert.Equal(t, "key-0100", string(it.Key()))
+			require.True(t, it.Next())
+			assert.Equal(t, "key-0102", string(it.Key()))
+			require.True(t, it.SeekLT([]byte("zzz")))
+			assert.Equal(t, "key-0198", string(it.Key()))
+			assert.False(t, it.SeekLT([]byte("key-0000")))
+		})
+	}
+}
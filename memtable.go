@@ -1,7 +1,24 @@
 package sstable
 
+// Memtable holds recent writes in memory until they are flushed to a table. When a key is written more than once
+// the write with the highest atomic count wins, whether it is an insert or a delete.
 type Memtable interface {
 	Searcher
 	Insert(key, value []byte, atomicCount uint64)
+	// Delete records a tombstone for key. The key is treated as absent from then on, and the tombstone is kept
+	// by ToSSTable so it also shadows the key in older tables.
+	Delete(key []byte, atomicCount uint64)
+	// ToSSTable returns the memtable's contents as a table, including tombstones so they shadow older tables.
 	ToSSTable(tableName string) *SSTable
+	// Len returns the number of distinct keys held, including deleted keys.
+	Len() int
+	// ApproximateSize returns an estimate of the memory held by the memtable's keys, values and nodes in bytes.
+	ApproximateSize() int64
+	// ShouldFlush reports whether the memtable has grown past the flush threshold it was created with.
+	ShouldFlush() bool
+}
+
+// shouldFlush reports whether size has reached threshold, where a threshold of zero or less never flushes.
+func shouldFlush(size, threshold int64) bool {
+	return threshold > 0 && size >= threshold
 }
@@ -0,0 +1,229 @@
+package sstable
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"os"
+	"runtime"
+	"testing"
+)
+
+var memtableTests = []struct {
+	name        string
+	newMemtable func(opts *MemtableOptions) Memtable
+	// allocatesChunks is set for memtables which report the size of the chunks they allocate from rather than of
+	// each entry
+	allocatesChunks bool
+}{
+	{"bst", func(opts *MemtableOptions) Memtable { return NewBstWithOptions(opts) }, false},
+	{"redBlackTree", func(opts *MemtableOptions) Memtable { return NewRedBlackTreeWithOptions(opts) }, false},
+	{"skipList", func(opts *MemtableOptions) Memtable { return NewSkipListWithOptions(opts) }, false},
+	{"arenaSkipList", func(opts *MemtableOptions) Memtable { return NewArenaSkipListWithOptions(opts) }, true},
+}
+
+func TestMemtable_Delete(t *testing.T) {
+	for _, tt := range memtableTests {
+		t.Run(tt.name, func(t *testing.T) {
+			memtable := tt.newMemtable(DefaultMemtableOptions())
+			memtable.Insert([]byte("A"), []byte("Alpha"), 1)
+			memtable.Insert([]byte("B"), []byte("Bravo"), 2)
+			memtable.Delete([]byte("B"), 3)
+			memtable.Delete([]byte("C"), 4)
+
+			_, err := memtable.Get([]byte("B"))
+			assert.ErrorIs(t, err, ErrDeleted)
+			found, err := memtable.Contains([]byte("B"))
+			require.NoError(t, err)
+			assert.False(t, found)
+			results, err := memtable.Scan()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"A"}, recordKeys(results))
+			results, err = memtable.ReverseScanWithLimit(nil)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"A"}, recordKeys(results))
+
+			// an insert older than the delete does not bring the key back, a newer one does
+			memtable.Insert([]byte("B"), []byte("Stale"), 2)
+			_, err = memtable.Get([]byte("B"))
+			assert.ErrorIs(t, err, ErrNotFound)
+			memtable.Delete([]byte("A"), 5)
+			memtable.Insert([]byte("A"), []byte("Apple"), 6)
+			rec, err := memtable.Get([]byte("A"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("Apple"), rec.Value)
+
+			table := memtable.ToSSTable("ExampleTest")
+			require.Len(t, table.Records, 3)
+			assert.False(t, table.Records[0].Deleted())
+			assert.True(t, table.Records[1].Deleted())
+			assert.True(t, table.Records[2].Deleted())
+		})
+	}
+}
+
+func TestMemtable_DeleteShadowsFlushedTable(t *testing.T) {
+	testTableName := "TestMemtableDeleteShadows"
+	newer := NewBst()
+	newer.Delete([]byte("A"), 2)
+
+	require.NoError(t, newer.ToSSTable("ExampleTest").SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+	defer os.Remove(testTableName)
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+	defer diskTable.Close()
+	// the tombstone is stored, so a reader consulting this table before older ones stops at it
+	_, err = diskTable.Get([]byte("A"))
+	assert.ErrorIs(t, err, ErrDeleted)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = diskTable.Get([]byte("B"))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrDeleted)
+}
+
+func TestMemtable_ApproximateSize(t *testing.T) {
+	for _, tt := range memtableTests {
+		t.Run(tt.name, func(t *testing.T) {
+			const threshold = 2 * arenaChunkSize
+			memtable := tt.newMemtable(&MemtableOptions{FlushThreshold: threshold})
+			assert.Equal(t, 0, memtable.Len())
+			empty := memtable.ApproximateSize()
+
+			memtable.Insert([]byte("key"), []byte("value"), 1)
+			size := memtable.ApproximateSize()
+			assert.Equal(t, 1, memtable.Len())
+
+			if tt.allocatesChunks {
+				// the first chunk is allocated up front and has room for every write below
+				assert.Equal(t, int64(arenaChunkSize), empty)
+				assert.Equal(t, empty, size)
+				memtable.Insert([]byte("key"), []byte("longer value"), 2)
+				memtable.Insert([]byte("key"), []byte("stale"), 1)
+				memtable.Delete([]byte("key"), 3)
+				assert.Equal(t, size, memtable.ApproximateSize())
+			} else {
+				// the size covers the key and value as well as the node holding them
+				assert.Equal(t, int64(0), empty)
+				assert.Greater(t, size, int64(len("key")+len("value")))
+				memtable.Insert([]byte("key"), []byte("longer value"), 2)
+				assert.Equal(t, size+int64(len("longer value")-len("value")), memtable.ApproximateSize())
+				memtable.Insert([]byte("key"), []byte("stale"), 1)
+				assert.Equal(t, size+int64(len("longer value")-len("value")), memtable.ApproximateSize())
+				memtable.Delete([]byte("key"), 3)
+				assert.Equal(t, size-int64(len("value")), memtable.ApproximateSize())
+			}
+			assert.Equal(t, 1, memtable.Len())
+			assert.False(t, memtable.ShouldFlush())
+
+			value := make([]byte, 1024)
+			for i := 0; !memtable.ShouldFlush(); i++ {
+				require.Less(t, i, 4096, "memtable never reached its flush threshold")
+				memtable.Insert([]byte(fmt.Sprintf("key-%04d", i)), value, uint64(i))
+			}
+			assert.GreaterOrEqual(t, memtable.ApproximateSize(), int64(threshold))
+		})
+	}
+}
+
+func TestMemtable_NoFlushThreshold(t *testing.T) {
+	for _, tt := range memtableTests {
+		t.Run(tt.name, func(t *testing.T) {
+			memtable := tt.newMemtable(&MemtableOptions{})
+			for i := 0; i < 1000; i++ {
+				memtable.Insert([]byte(fmt.Sprintf("key-%04d", i)), []byte("value"), uint64(i))
+			}
+			assert.Equal(t, 1000, memtable.Len())
+			assert.False(t, memtable.ShouldFlush())
+		})
+	}
+}
+
+func TestMemtable_NilOptions(t *testing.T) {
+	for _, tt := range memtableTests {
+		t.Run(tt.name, func(t *testing.T) {
+			memtable := tt.newMemtable(nil)
+			memtable.Insert([]byte("key"), []byte("value"), 1)
+			rec, err := memtable.Get([]byte("key"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("value"), rec.Value)
+			assert.False(t, memtable.ShouldFlush())
+		})
+	}
+}
+
+// benchmarkKeys returns n keys, either in sorted order or shuffled.
+func benchmarkKeys(n int, random bool) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%08d", i))
+	}
+	if random {
+		rand.New(rand.NewSource(1)).Shuffle(n, func(i, j int) {
+			keys[i], keys[j] = keys[j], keys[i]
+		})
+	}
+	return keys
+}
+
+func BenchmarkMemtable_Insert(b *testing.B) {
+	for _, bm := range memtableTests {
+		for _, random := range []bool{false, true} {
+			keys := benchmarkKeys(5000, random)
+			b.Run(fmt.Sprintf("%s/random=%t", bm.name, random), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					memtable := bm.newMemtable(DefaultMemtableOptions())
+					for j, key := range keys {
+						memtable.Insert(key, key, uint64(j))
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkMemtable_Get(b *testing.B) {
+	for _, bm := range memtableTests {
+		for _, random := range []bool{false, true} {
+			keys := benchmarkKeys(5000, random)
+			memtable := bm.newMemtable(DefaultMemtableOptions())
+			for j, key := range keys {
+				memtable.Insert(key, key, uint64(j))
+			}
+			b.Run(fmt.Sprintf("%s/random=%t", bm.name, random), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := memtable.Get(keys[i%len(keys)]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkMemtable_GC(b *testing.B) {
+	for _, bm := range memtableTests {
+		b.Run(bm.name, func(b *testing.B) {
+			memtable := bm.newMemtable(DefaultMemtableOptions())
+			for i, key := range benchmarkKeys(200000, true) {
+				memtable.Insert(key, key, uint64(i))
+			}
+			runtime.GC()
+
+			// each collection has to mark every object the memtable holds, so ns/op grows with the heap objects
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+			}
+			b.StopTimer()
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			b.ReportMetric(float64(stats.HeapObjects), "heap-objects")
+			b.ReportMetric(float64(stats.HeapAlloc), "heap-bytes")
+			runtime.KeepAlive(memtable)
+		})
+	}
+}
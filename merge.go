@@ -1,25 +1,31 @@
 package sstable
 
-import "bytes"
-
+// MergeTables merges two tables into one named after first. When both hold a key the write with the highest atomic
+// count wins, and deleted keys are dropped, so it should only be used when no older tables remain to be shadowed.
 func MergeTables(first, second *SSTable) *SSTable {
+	return MergeTablesWithOptions(first, second, nil)
+}
+
+// MergeTablesWithOptions merges two tables into one named after first, as MergeTables does, with opts deciding
+// whether deleted keys are kept. A nil opts is the same as the zero MergeOptions.
+func MergeTablesWithOptions(first, second *SSTable, opts *MergeOptions) *SSTable {
+	if opts == nil {
+		opts = &MergeOptions{}
+	}
 	mapCapacity := len(first.Records) + len(second.Records)
 	mapping := make(map[string]*Record, mapCapacity)
-	for _, rec := range first.Records {
-		key := string(rec.Key)
-		val, ok := mapping[key]
-		if !ok {
-			mapping[key] = rec
-		} else {
-			if val.AtomicCount < rec.AtomicCount {
+	for _, table := range []*SSTable{first, second} {
+		for _, rec := range table.Records {
+			key := string(rec.Key)
+			val, ok := mapping[key]
+			if !ok || val.AtomicCount < rec.AtomicCount {
 				mapping[key] = rec
 			}
 		}
 	}
 	newRecordSet := make([]*Record, 0, len(mapping))
 	for _, val := range mapping {
-		// filter out deleted records from the new SSTable
-		if bytes.Compare(val.Value, TombstoneMarker) != 0 {
+		if opts.KeepTombstones || !val.Deleted() {
 			newRecordSet = append(newRecordSet, val)
 		}
 	}
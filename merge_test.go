@@ -0,0 +1,34 @@
+package sstable
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMergeTables(t *testing.T) {
+	older := NewSSTable("Older", []*Record{
+		NewRecordWithCount([]byte("A"), []byte("Alpha"), 1),
+		NewRecordWithCount([]byte("B"), []byte("Bravo"), 2),
+		NewRecordWithCount([]byte("C"), []byte("Charlie"), 3),
+	})
+	newer := NewSSTable("Newer", []*Record{
+		NewRecordWithCount([]byte("A"), []byte("Apple"), 4),
+		NewTombstoneWithCount([]byte("B"), 5),
+		NewRecordWithCount([]byte("D"), []byte("Delta"), 6),
+	})
+
+	// the newest write of each key wins, whichever table it came from
+	for _, merged := range []*SSTable{MergeTables(newer, older), MergeTables(older, newer)} {
+		assert.Equal(t, []string{"A", "C", "D"}, recordKeys(merged.Records))
+		rec, err := merged.Get([]byte("A"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("Apple"), rec.Value)
+	}
+	assert.Equal(t, []byte("Newer"), MergeTables(newer, older).Metadata.TableName)
+
+	merged := MergeTablesWithOptions(newer, older, &MergeOptions{KeepTombstones: true})
+	assert.Equal(t, []string{"A", "B", "C", "D"}, recordKeys(merged.Records))
+	_, err := merged.Get([]byte("B"))
+	assert.ErrorIs(t, err, ErrDeleted)
+}
@@ -8,54 +8,82 @@ var (
 	byteOrdering = binary.LittleEndian
 )
 
+// TableMeta describes a table as a whole. It is stored in its own block, located by the footer.
 type TableMeta struct {
-	DiskSize  uint32
-	KeyCount  uint32
-	Offsets   []uint32
+	KeyCount  uint64
+	BlockSize uint32
 	TableName []byte
+	// PrefixExtractor is the name of the extractor whose prefixes were added to the filter, if any.
+	PrefixExtractor []byte
 }
 
 func (t *TableMeta) Size() int {
-	return 8 + int(4*t.KeyCount) + len(t.TableName)
+	return len(t.appendTo(nil))
 }
 
-func NewTableMeta(tableName string, size uint32) *TableMeta {
-	t := &TableMeta{
+func NewTableMeta(tableName string, size uint64) *TableMeta {
+	return &TableMeta{
 		KeyCount:  size,
-		Offsets:   make([]uint32, size),
 		TableName: []byte(tableName),
 	}
-	t.DiskSize = uint32(t.Size())
-	return t
 }
 
-func (t *TableMeta) ToBytes() ([]byte, error) {
-	contents := make([]byte, t.Size())
-	offset := 0
-	byteOrdering.PutUint32(contents[offset:], t.DiskSize)
-	offset += 4
-	byteOrdering.PutUint32(contents[offset:], t.KeyCount)
-	offset += 4
-	for _, off := range t.Offsets {
-		byteOrdering.PutUint32(contents[offset:], off)
-		offset += 4
-	}
-	copy(contents[offset:], t.TableName)
-	return contents, nil
+func (t *TableMeta) appendTo(contents []byte) []byte {
+	contents = byteOrdering.AppendUint64(contents, t.KeyCount)
+	contents = byteOrdering.AppendUint32(contents, t.BlockSize)
+	for _, field := range [][]byte{t.TableName, t.PrefixExtractor} {
+		contents = binary.AppendUvarint(contents, uint64(len(field)))
+		contents = append(contents, field...)
+	}
+	return contents
 }
 
-func TableMetaFromBytes(contents []byte) *TableMeta {
-	t := &TableMeta{}
+func (t *TableMeta) ToBytes() ([]byte, error) {
+	return t.appendTo(nil), nil
+}
 
-	t.DiskSize = byteOrdering.Uint32(contents)
-	t.KeyCount = byteOrdering.Uint32(contents[4:])
+// tableMetaFromBytes decodes the metadata of a table written with the given format version. Versions 1 and 2
+// stored the key count, as 32 and 64 bits respectively, followed by the block size and the table name, filling the
+// rest of the block. Version 3 added the prefix extractor, with both names length prefixed.
+func tableMetaFromBytes(contents []byte, version uint32) (*TableMeta, error) {
+	if version > 2 {
+		return TableMetaFromBytes(contents)
+	}
+	countSize := 4 * int(version)
+	if len(contents) < countSize+4 {
+		return nil, newCorruptionError(0, "table metadata is truncated")
+	}
+	t := &TableMeta{
+		BlockSize: byteOrdering.Uint32(contents[countSize:]),
+		TableName: contents[countSize+4:],
+	}
+	if version == 1 {
+		t.KeyCount = uint64(byteOrdering.Uint32(contents))
+	} else {
+		t.KeyCount = byteOrdering.Uint64(contents)
+	}
+	return t, nil
+}
 
-	t.Offsets = make([]uint32, t.KeyCount)
-	offset := 8
-	for i := 0; i < int(t.KeyCount); i++ {
-		t.Offsets[i] = byteOrdering.Uint32(contents[offset:])
-		offset += 4
+// TableMetaFromBytes decodes metadata written by TableMeta.ToBytes. The metadata block has already had its
+// checksum verified by the time it is decoded.
+func TableMetaFromBytes(contents []byte) (*TableMeta, error) {
+	if len(contents) < 12 {
+		return nil, newCorruptionError(0, "table metadata is truncated")
+	}
+	t := &TableMeta{
+		KeyCount:  byteOrdering.Uint64(contents),
+		BlockSize: byteOrdering.Uint32(contents[8:]),
+	}
+	offset := 12
+	for _, field := range []*[]byte{&t.TableName, &t.PrefixExtractor} {
+		size, n := binary.Uvarint(contents[offset:])
+		if n <= 0 || size > uint64(len(contents)-offset-n) {
+			return nil, newCorruptionError(int64(offset), "table metadata field is truncated")
+		}
+		offset += n
+		*field = contents[offset : offset+int(size)]
+		offset += int(size)
 	}
-	t.TableName = contents[offset:]
-	return t
+	return t, nil
 }
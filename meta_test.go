@@ -8,12 +8,27 @@ import (
 func TestNewTableMeta(t *testing.T) {
 
 	res := NewTableMeta("hello_test", 3)
+	res.BlockSize = DefaultBlockSize
+	res.PrefixExtractor = []byte(FixedPrefixExtractor(4).Name)
 
 	contents, err := res.ToBytes()
 	assert.NoError(t, err)
 
-	result := TableMetaFromBytes(contents)
-	assert.Equal(t, res.TableName, result.TableName)
-	assert.Equal(t, res.KeyCount, result.KeyCount)
-	assert.Equal(t, res.Offsets, result.Offsets)
+	result, err := TableMetaFromBytes(contents)
+	assert.NoError(t, err)
+	assert.Equal(t, res, result)
+}
+
+func TestTableMetaFromBytes_Truncated(t *testing.T) {
+	contents, err := NewTableMeta("hello_test", 3).ToBytes()
+	assert.NoError(t, err)
+
+	_, err = TableMetaFromBytes(contents[:5])
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
+
+	for i := 0; i < len(contents); i++ {
+		_, err = TableMetaFromBytes(contents[:i])
+		assert.ErrorIs(t, err, ErrCorrupt)
+	}
 }
@@ -0,0 +1,76 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// legacyTombstoneMarker is the value tables written before record kinds existed used to mark a deleted key.
+var legacyTombstoneMarker = []byte("#DELETED#")
+
+var errLegacyTruncated = fmt.Errorf("%w: legacy table is truncated", ErrCorrupt)
+
+// MigrateLegacyTable rewrites a table written in the legacy layout, where records carried no kind and deletions
+// were stored as the "#DELETED#" value, into the current layout. Values equal to the legacy marker are converted
+// into tombstones, as that is how the legacy format interpreted them.
+func MigrateLegacyTable(fileName string, nameFunc TableNameFunc) error {
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	tableName, records, err := legacyTableFromBytes(contents)
+	if err != nil {
+		return err
+	}
+	return NewSSTable(tableName, records).SaveToDisk(nameFunc)
+}
+
+func legacyTableFromBytes(contents []byte) (string, []*Record, error) {
+	if len(contents) < 8 {
+		return "", nil, errLegacyTruncated
+	}
+	metaSize := byteOrdering.Uint32(contents)
+	keyCount := byteOrdering.Uint32(contents[4:])
+	if int(metaSize) > len(contents) || 8+4*int(keyCount) > int(metaSize) {
+		return "", nil, errLegacyTruncated
+	}
+
+	offset := 8
+	records := make([]*Record, 0, keyCount)
+	for i := 0; i < int(keyCount); i++ {
+		rec, err := legacyRecordFromBytes(contents, int(byteOrdering.Uint32(contents[offset:])))
+		if err != nil {
+			return "", nil, err
+		}
+		records = append(records, rec)
+		offset += 4
+	}
+	return string(contents[offset:metaSize]), records, nil
+}
+
+func legacyRecordFromBytes(contents []byte, offset int) (*Record, error) {
+	if offset+4 > len(contents) {
+		return nil, errLegacyTruncated
+	}
+	keySize := int(byteOrdering.Uint32(contents[offset:]))
+	offset += 4
+	if offset+keySize+4 > len(contents) {
+		return nil, errLegacyTruncated
+	}
+	key := contents[offset : offset+keySize]
+	offset += keySize
+	valueSize := int(byteOrdering.Uint32(contents[offset:]))
+	offset += 4
+	if offset+valueSize+8 > len(contents) {
+		return nil, errLegacyTruncated
+	}
+	value := contents[offset : offset+valueSize]
+	offset += valueSize
+	count := byteOrdering.Uint64(contents[offset:])
+
+	if bytes.Equal(value, legacyTombstoneMarker) {
+		return NewTombstoneWithCount(key, count), nil
+	}
+	return NewRecordWithCount(key, value, count), nil
+}
@@ -0,0 +1,63 @@
+package sstable
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func legacyTableBytes(tableName string, keys, values [][]byte) []byte {
+	metaSize := 8 + 4*len(keys) + len(tableName)
+	var records []byte
+	offsets := make([]byte, 0, 4*len(keys))
+	for i := range keys {
+		offsets = byteOrdering.AppendUint32(offsets, uint32(metaSize+len(records)))
+		records = byteOrdering.AppendUint32(records, uint32(len(keys[i])))
+		records = append(records, keys[i]...)
+		records = byteOrdering.AppendUint32(records, uint32(len(values[i])))
+		records = append(records, values[i]...)
+		records = byteOrdering.AppendUint64(records, uint64(i+1))
+	}
+	contents := byteOrdering.AppendUint32(nil, uint32(metaSize))
+	contents = byteOrdering.AppendUint32(contents, uint32(len(keys)))
+	contents = append(contents, offsets...)
+	contents = append(contents, tableName...)
+	return append(contents, records...)
+}
+
+func TestMigrateLegacyTable(t *testing.T) {
+	legacyName := "TestMigrateLegacyTableSource"
+	migratedName := "TestMigrateLegacyTableDest"
+
+	contents := legacyTableBytes("legacy", [][]byte{
+		[]byte("A"),
+		[]byte("B"),
+		[]byte("C"),
+	}, [][]byte{
+		[]byte("Alpha"),
+		legacyTombstoneMarker,
+		[]byte("Charlie"),
+	})
+	require.NoError(t, os.WriteFile(legacyName, contents, 0644))
+	defer os.Remove(legacyName)
+
+	err := MigrateLegacyTable(legacyName, func(tableName string) string {
+		assert.Equal(t, "legacy", tableName)
+		return migratedName
+	})
+	require.NoError(t, err)
+	defer os.Remove(migratedName)
+
+	diskTable, err := NewDiskTable(migratedName)
+	require.NoError(t, err)
+
+	found, err := diskTable.Contains([]byte("B"))
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	rec, err := diskTable.Get([]byte("C"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("Charlie"), rec.Value)
+	assert.Equal(t, uint64(3), rec.AtomicCount)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package sstable
+
+import "os"
+
+func mmap(file *os.File, size int) ([]byte, error) {
+	return nil, ErrMmapUnsupported
+}
+
+func munmap(data []byte) error {
+	return ErrMmapUnsupported
+}
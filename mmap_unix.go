@@ -0,0 +1,17 @@
+//go:build unix
+
+package sstable
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of the file read only.
+func mmap(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,97 @@
+package sstable
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultBlockSize is the target size of data blocks written when no WriteOptions are supplied.
+const DefaultBlockSize = 4 * 1024
+
+// DefaultBloomBitsPerKey is the Bloom filter size used when no WriteOptions are supplied.
+const DefaultBloomBitsPerKey = 10
+
+// DefaultRestartInterval is the number of keys between restart points used when no WriteOptions are supplied.
+const DefaultRestartInterval = 16
+
+// WriteOptions controls the layout of tables produced by SSTable.ToBytes and SSTable.SaveToDisk.
+type WriteOptions struct {
+	// BlockSize is the target size in bytes of each data block. A block is cut once it reaches this size, so it
+	// may exceed the target by up to one record.
+	BlockSize int
+	// RestartInterval is the number of keys between restart points within a data block. Keys between restart
+	// points only store the suffix which differs from the previous key, so larger intervals give smaller files
+	// at the cost of decoding more entries per lookup.
+	RestartInterval int
+	// Compression is the codec data blocks are compressed with, a nil codec leaves blocks uncompressed. Blocks
+	// which do not shrink when compressed are always stored as they are.
+	Compression Codec
+	// BloomBitsPerKey is the number of Bloom filter bits stored per key, letting lookups for absent keys skip
+	// reading any data blocks. Zero disables the filter.
+	BloomBitsPerKey int
+	// PrefixExtractor, when set alongside a Bloom filter, adds the prefix of every key to the filter so prefix
+	// scans can rule out the table.
+	PrefixExtractor *PrefixExtractor
+}
+
+func DefaultWriteOptions() *WriteOptions {
+	return &WriteOptions{
+		BlockSize:       DefaultBlockSize,
+		RestartInterval: DefaultRestartInterval,
+		BloomBitsPerKey: DefaultBloomBitsPerKey,
+	}
+}
+
+// ReadOptions controls how a DiskTable is read.
+type ReadOptions struct {
+	// PrefixExtractor enables the prefix filter of tables written with an extractor of the same name.
+	PrefixExtractor *PrefixExtractor
+	// UseMmap memory maps the table rather than reading blocks with a system call per read. Values of records
+	// read from uncompressed blocks alias the mapping and must not be used after the table is closed.
+	UseMmap bool
+	// BlockCache, when set, caches decoded blocks of the table. A single cache is usually shared by every table.
+	// It is not used by memory mapped tables, whose blocks are already in memory.
+	BlockCache *BlockCache
+	// PinIndexAndFilter keeps the index and filter in memory for the lifetime of the table when a BlockCache is
+	// used, charging them to the cache without letting them be evicted. Otherwise they are loaded through the
+	// cache like data blocks. Tables without a BlockCache always keep them in memory.
+	PinIndexAndFilter bool
+}
+
+func DefaultReadOptions() *ReadOptions {
+	return &ReadOptions{}
+}
+
+func (o *WriteOptions) validate() error {
+	if o.BlockSize <= 0 || uint64(o.BlockSize) > math.MaxUint32 {
+		return fmt.Errorf("sstable: block size must be between 1 and %d bytes, got %d", uint32(math.MaxUint32), o.BlockSize)
+	}
+	if o.BloomBitsPerKey < 0 {
+		return fmt.Errorf("sstable: bloom bits per key must not be negative, got %d", o.BloomBitsPerKey)
+	}
+	return nil
+}
+
+// DefaultFlushThreshold is the approximate memtable size at which it should be flushed when no MemtableOptions
+// are supplied.
+const DefaultFlushThreshold = 4 * 1024 * 1024
+
+// MemtableOptions controls when a memtable reports it should be flushed.
+type MemtableOptions struct {
+	// FlushThreshold is the approximate size in bytes at which ShouldFlush starts reporting true, signalling that
+	// the memtable should be frozen and written out with ToSSTable. Zero or less never signals a flush.
+	FlushThreshold int64
+}
+
+func DefaultMemtableOptions() *MemtableOptions {
+	return &MemtableOptions{
+		FlushThreshold: DefaultFlushThreshold,
+	}
+}
+
+// MergeOptions controls how MergeTables combines tables.
+type MergeOptions struct {
+	// KeepTombstones keeps deleted keys in the merged table so they still shadow the key in older tables which
+	// were not part of the merge. Only a merge which includes the oldest table may drop them.
+	KeepTombstones bool
+}
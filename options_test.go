@@ -0,0 +1,13 @@
+package sstable
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWriteOptions_Validate(t *testing.T) {
+	opts := DefaultWriteOptions()
+	opts.BlockSize = 0
+	_, err := NewSSTableWithOptions("ExampleTest", nil, opts).ToBytes()
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PrefixFunc returns the prefix of key, or false if key has no prefix and should be left out of prefix filters.
+type PrefixFunc func(key []byte) ([]byte, bool)
+
+// PrefixExtractor splits keys into the prefixes added to a table's filter, letting prefix scans skip tables which
+// hold no key with the prefix. Every key starting with a prefix the extractor produces must itself be mapped to
+// that same prefix.
+//
+// The Name is recorded in the table, and readers only trust a table's prefix filter when they are configured
+// with an extractor of the same name, so an extractor must be renamed whenever its behaviour changes.
+type PrefixExtractor struct {
+	Name   string
+	Prefix PrefixFunc
+}
+
+// FixedPrefixExtractor uses the first length bytes of each key as its prefix. Shorter keys have no prefix.
+func FixedPrefixExtractor(length int) *PrefixExtractor {
+	return &PrefixExtractor{
+		Name: fmt.Sprintf("sstable.FixedPrefix:%d", length),
+		Prefix: func(key []byte) ([]byte, bool) {
+			if len(key) < length {
+				return nil, false
+			}
+			return key[:length], true
+		},
+	}
+}
+
+// isPrefix reports whether prefix is exactly a prefix the extractor produces, which is required before a prefix
+// filter can be used to rule it out.
+func (p *PrefixExtractor) isPrefix(prefix []byte) bool {
+	extracted, ok := p.Prefix(prefix)
+	return ok && bytes.Equal(extracted, prefix)
+}
@@ -0,0 +1,63 @@
+package sstable
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestDiskTable_ScanPrefix(t *testing.T) {
+	testTableName := "TestDiskTableScanPrefix"
+
+	var allRecords []*Record
+	for tenant := 0; tenant < 10; tenant++ {
+		for entity := 0; entity < 50; entity++ {
+			key := []byte(fmt.Sprintf("t%03d/e%04d", tenant*2, entity))
+			allRecords = append(allRecords, NewRecordWithCount(key, key, uint64(len(allRecords))))
+		}
+	}
+	tableRecords := append([]*Record{NewTombstoneWithCount([]byte("t004/e9999"), 1000)}, allRecords...)
+
+	extractor := FixedPrefixExtractor(5)
+	opts := DefaultWriteOptions()
+	opts.BlockSize = 256
+	opts.PrefixExtractor = extractor
+	table := NewSSTableWithOptions("ExampleTest", tableRecords, opts)
+	require.NoError(t, table.SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+	defer os.Remove(testTableName)
+
+	diskTable, err := NewDiskTableWithOptions(testTableName, &ReadOptions{PrefixExtractor: extractor})
+	require.NoError(t, err)
+	assert.Equal(t, []byte(extractor.Name), diskTable.TableMeta.PrefixExtractor)
+
+	results, err := diskTable.ScanPrefix([]byte("t004/"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, allRecords[100:150], results)
+
+	results, err = diskTable.ScanPrefix([]byte("t004/"), &Limit{MaxResults: 3})
+	require.NoError(t, err)
+	assert.Equal(t, allRecords[100:103], results)
+
+	// prefixes shorter than the extractor's cannot use the filter but must still be answered correctly
+	assert.True(t, diskTable.MayContainPrefix([]byte("t00")))
+	results, err = diskTable.ScanPrefix([]byte("t00"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, allRecords[:250], results)
+
+	falsePositives := 0
+	for tenant := 0; tenant < 100; tenant++ {
+		if diskTable.MayContainPrefix([]byte(fmt.Sprintf("t%03d/", tenant*2+1))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 10)
+
+	other := &PrefixExtractor{Name: "other", Prefix: extractor.Prefix}
+	diskTable, err = NewDiskTableWithOptions(testTableName, &ReadOptions{PrefixExtractor: other})
+	require.NoError(t, err)
+	assert.True(t, diskTable.MayContainPrefix([]byte("t001/")))
+}
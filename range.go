@@ -0,0 +1,83 @@
+package sstable
+
+import "bytes"
+
+// KeyRange bounds a range scan. A nil Start or End leaves that side of the range unbounded. The zero value of the
+// flags gives the half open range [Start, End).
+type KeyRange struct {
+	Start          []byte
+	End            []byte
+	StartExclusive bool
+	EndInclusive   bool
+}
+
+func (r *KeyRange) afterStart(key []byte) bool {
+	if r.Start == nil {
+		return true
+	}
+	cmp := bytes.Compare(key, r.Start)
+	return cmp > 0 || (cmp == 0 && !r.StartExclusive)
+}
+
+func (r *KeyRange) beforeEnd(key []byte) bool {
+	if r.End == nil {
+		return true
+	}
+	cmp := bytes.Compare(key, r.End)
+	return cmp < 0 || (cmp == 0 && r.EndInclusive)
+}
+
+// seekStart positions the iterator on the first key within the range, if there is one.
+func (r *KeyRange) seekStart(it Iterator) bool {
+	if r.Start == nil {
+		return it.First()
+	}
+	ok := it.SeekGE(r.Start)
+	if ok && r.StartExclusive && bytes.Equal(it.Key(), r.Start) {
+		ok = it.Next()
+	}
+	return ok
+}
+
+// seekEnd positions the iterator on the last key within the range, if there is one.
+func (r *KeyRange) seekEnd(it Iterator) bool {
+	if r.End == nil {
+		return it.Last()
+	}
+	if r.EndInclusive && it.SeekGE(r.End) && bytes.Equal(it.Key(), r.End) {
+		return true
+	}
+	return it.SeekLT(r.End)
+}
+
+// scanIterator collects up to limit live records within the range which match pred from it, which it closes,
+// walking backwards from the end of the range when reverse is set. A nil range or predicate matches every record.
+// Seeking to the range bound means only records within it are read, and the scan stops as soon as the limit is
+// reached.
+func scanIterator(it Iterator, r *KeyRange, pred Predicate, limit *Limit, reverse bool) ([]*Record, error) {
+	defer it.Close()
+	if r == nil {
+		r = &KeyRange{}
+	}
+	maxResults := limitValue(limit)
+
+	results := []*Record{}
+	collect := func() {
+		if pred == nil || pred(it.Key(), it.Value()) {
+			results = append(results, it.Record())
+		}
+	}
+	if reverse {
+		for ok := r.seekEnd(it); ok && r.afterStart(it.Key()) && len(results) < maxResults; ok = it.Prev() {
+			collect()
+		}
+	} else {
+		for ok := r.seekStart(it); ok && r.beforeEnd(it.Key()) && len(results) < maxResults; ok = it.Next() {
+			collect()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,100 @@
+package sstable
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func recordKeys(records []*Record) []string {
+	keys := make([]string, 0, len(records))
+	for _, rec := range records {
+		keys = append(keys, string(rec.Key))
+	}
+	return keys
+}
+
+func TestSearcher_ScanRange(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Range    *KeyRange
+		Limit    *Limit
+		Expected []string
+		Reverse  []string
+	}{
+		{
+			Name:     "half open",
+			Range:    &KeyRange{Start: []byte("key-0010"), End: []byte("key-0016")},
+			Expected: []string{"key-0010", "key-0012", "key-0014"},
+			Reverse:  []string{"key-0014", "key-0012", "key-0010"},
+		},
+		{
+			Name:     "exclusive start inclusive end",
+			Range:    &KeyRange{Start: []byte("key-0010"), End: []byte("key-0016"), StartExclusive: true, EndInclusive: true},
+			Expected: []string{"key-0012", "key-0014", "key-0016"},
+			Reverse:  []string{"key-0016", "key-0014", "key-0012"},
+		},
+		{
+			Name:     "bounds on deleted keys",
+			Range:    &KeyRange{Start: []byte("key-0011"), End: []byte("key-0015"), EndInclusive: true},
+			Expected: []string{"key-0012", "key-0014"},
+			Reverse:  []string{"key-0014", "key-0012"},
+		},
+		{
+			Name:     "unbounded start with limit",
+			Range:    &KeyRange{End: []byte("key-0100")},
+			Limit:    &Limit{MaxResults: 2},
+			Expected: []string{"key-0000", "key-0002"},
+			Reverse:  []string{"key-0098", "key-0096"},
+		},
+		{
+			Name:     "unbounded end with limit",
+			Range:    &KeyRange{Start: []byte("key-0190")},
+			Limit:    &Limit{MaxResults: 3},
+			Expected: []string{"key-0190", "key-0192", "key-0194"},
+			Reverse:  []string{"key-0198", "key-0196", "key-0194"},
+		},
+		{
+			Name:     "empty",
+			Range:    &KeyRange{Start: []byte("key-0050"), End: []byte("key-0040")},
+			Expected: []string{},
+			Reverse:  []string{},
+		},
+	}
+
+	for name, searcher := range iteratorTestSearchers(t) {
+		for _, tc := range testCases {
+			t.Run(name+" "+tc.Name, func(t *testing.T) {
+				results, err := searcher.ScanRange(tc.Range, tc.Limit)
+				require.NoError(t, err)
+				assert.Equal(t, tc.Expected, recordKeys(results))
+
+				results, err = searcher.ReverseScanRange(tc.Range, tc.Limit)
+				require.NoError(t, err)
+				assert.Equal(t, tc.Reverse, recordKeys(results))
+			})
+		}
+	}
+}
+
+func TestSearcher_ReverseScan(t *testing.T) {
+	for name, searcher := range iteratorTestSearchers(t) {
+		t.Run(name, func(t *testing.T) {
+			results, err := searcher.ReverseScanWithLimit(&Limit{MaxResults: 3})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"key-0198", "key-0196", "key-0194"}, recordKeys(results))
+
+			results, err = searcher.ReverseScanWithLimit(nil)
+			require.NoError(t, err)
+			assert.Len(t, results, 100)
+			assert.Equal(t, "key-0000", string(results[99].Key))
+
+			endsInZero := func(key, value []byte) bool {
+				return key[len(key)-1] == '0'
+			}
+			results, err = searcher.ReverseScanWithPredicate(endsInZero, &Limit{MaxResults: 2})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"key-0190", "key-0180"}, recordKeys(results))
+		})
+	}
+}
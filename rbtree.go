@@ -0,0 +1,398 @@
+package sstable
+
+import (
+	"bytes"
+	"unsafe"
+)
+
+// RedBlackTree is a memtable kept balanced as a red-black tree, so inserts and lookups take O(log n) time however
+// the keys arrive, including in sorted order where a Bst degrades into a linked list. Every operation walks the
+// tree iteratively, using parent links rather than recursion or an explicit stack.
+//
+// Writing a key which is already present keeps whichever write has the higher atomic count, so a delete only
+// shadows inserts older than it.
+type RedBlackTree struct {
+	root           *rbNode
+	count          int
+	size           int64
+	flushThreshold int64
+}
+
+type rbNode struct {
+	kind        RecordKind
+	key         []byte
+	value       []byte
+	atomicCount uint64
+	red         bool
+	left        *rbNode
+	right       *rbNode
+	parent      *rbNode
+}
+
+// rbNodeOverhead is the memory a node takes up besides its key and value.
+const rbNodeOverhead = int64(unsafe.Sizeof(rbNode{}))
+
+func NewRedBlackTree() *RedBlackTree {
+	return NewRedBlackTreeWithOptions(DefaultMemtableOptions())
+}
+
+// NewRedBlackTreeWithOptions creates an empty tree which signals a flush according to opts. A nil opts is the same
+// as DefaultMemtableOptions.
+func NewRedBlackTreeWithOptions(opts *MemtableOptions) *RedBlackTree {
+	if opts == nil {
+		opts = DefaultMemtableOptions()
+	}
+	return &RedBlackTree{flushThreshold: opts.FlushThreshold}
+}
+
+// Len returns the number of distinct keys in the tree, including deleted keys.
+func (t *RedBlackTree) Len() int {
+	return t.count
+}
+
+func (t *RedBlackTree) ApproximateSize() int64 {
+	return t.size
+}
+
+func (t *RedBlackTree) ShouldFlush() bool {
+	return shouldFlush(t.size, t.flushThreshold)
+}
+
+func (t *RedBlackTree) Insert(key, value []byte, atomicCount uint64) {
+	t.insert(KindPut, key, value, atomicCount)
+}
+
+func (t *RedBlackTree) Delete(key []byte, atomicCount uint64) {
+	t.insert(KindDelete, key, nil, atomicCount)
+}
+
+func (t *RedBlackTree) insert(kind RecordKind, key, value []byte, atomicCount uint64) {
+	var parent *rbNode
+	node := t.root
+	cmp := 0
+	for node != nil {
+		parent = node
+		cmp = bytes.Compare(key, node.key)
+		if cmp == 0 {
+			if atomicCount > node.atomicCount {
+				t.size += int64(len(value) - len(node.value))
+				node.kind = kind
+				node.value = value
+				node.atomicCount = atomicCount
+			}
+			return
+		}
+		if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+
+	node = &rbNode{kind: kind, key: key, value: value, atomicCount: atomicCount, red: true, parent: parent}
+	switch {
+	case parent == nil:
+		t.root = node
+	case cmp < 0:
+		parent.left = node
+	default:
+		parent.right = node
+	}
+	t.count++
+	t.size += rbNodeOverhead + int64(len(key)+len(value))
+	t.insertFixup(node)
+}
+
+// insertFixup restores the red-black properties after node has been inserted red, recolouring while its uncle is
+// red and otherwise rotating the red node into place under a black parent.
+func (t *RedBlackTree) insertFixup(node *rbNode) {
+	for isRed(node.parent) {
+		parent := node.parent
+		grandparent := parent.parent
+		if parent == grandparent.left {
+			if uncle := grandparent.right; isRed(uncle) {
+				parent.red, uncle.red, grandparent.red = false, false, true
+				node = grandparent
+				continue
+			}
+			if node == parent.right {
+				node = parent
+				t.rotateLeft(node)
+				parent = node.parent
+			}
+			parent.red, grandparent.red = false, true
+			t.rotateRight(grandparent)
+		} else {
+			if uncle := grandparent.left; isRed(uncle) {
+				parent.red, uncle.red, grandparent.red = false, false, true
+				node = grandparent
+				continue
+			}
+			if node == parent.left {
+				node = parent
+				t.rotateRight(node)
+				parent = node.parent
+			}
+			parent.red, grandparent.red = false, true
+			t.rotateLeft(grandparent)
+		}
+	}
+	t.root.red = false
+}
+
+func isRed(node *rbNode) bool {
+	return node != nil && node.red
+}
+
+func (t *RedBlackTree) rotateLeft(node *rbNode) {
+	child := node.right
+	node.right = child.left
+	if child.left != nil {
+		child.left.parent = node
+	}
+	t.replaceChild(node, child)
+	child.left = node
+	node.parent = child
+}
+
+func (t *RedBlackTree) rotateRight(node *rbNode) {
+	child := node.left
+	node.left = child.right
+	if child.right != nil {
+		child.right.parent = node
+	}
+	t.replaceChild(node, child)
+	child.right = node
+	node.parent = child
+}
+
+// replaceChild puts child where node was in the tree.
+func (t *RedBlackTree) replaceChild(node, child *rbNode) {
+	child.parent = node.parent
+	switch {
+	case node.parent == nil:
+		t.root = child
+	case node == node.parent.left:
+		node.parent.left = child
+	default:
+		node.parent.right = child
+	}
+}
+
+func (t *RedBlackTree) search(key []byte) *rbNode {
+	node := t.root
+	for node != nil {
+		cmp := bytes.Compare(key, node.key)
+		if cmp == 0 {
+			return node
+		}
+		if cmp < 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	return nil
+}
+
+func (t *RedBlackTree) Contains(key []byte) (bool, error) {
+	node := t.search(key)
+	return node != nil && node.kind != KindDelete, nil
+}
+
+func (t *RedBlackTree) Get(key []byte) (*Record, error) {
+	node := t.search(key)
+	if node == nil {
+		return nil, ErrNotFound
+	}
+	if node.kind == KindDelete {
+		return nil, ErrDeleted
+	}
+	return node.record(), nil
+}
+
+func (n *rbNode) record() *Record {
+	rec := NewRecordWithCount(n.key, n.value, n.atomicCount)
+	rec.Kind = n.kind
+	return rec
+}
+
+func (t *RedBlackTree) Scan() ([]*Record, error) {
+	return scanIterator(t.NewIterator(), nil, nil, nil, false)
+}
+
+func (t *RedBlackTree) ScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(t.NewIterator(), nil, nil, limit, false)
+}
+
+func (t *RedBlackTree) ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(t.NewIterator(), nil, pred, limit, false)
+}
+
+func (t *RedBlackTree) ReverseScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(t.NewIterator(), nil, nil, limit, true)
+}
+
+func (t *RedBlackTree) ReverseScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(t.NewIterator(), nil, pred, limit, true)
+}
+
+func (t *RedBlackTree) ScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(t.NewIterator(), r, nil, limit, false)
+}
+
+func (t *RedBlackTree) ReverseScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(t.NewIterator(), r, nil, limit, true)
+}
+
+func (t *RedBlackTree) ToSSTable(tableName string) *SSTable {
+	records := make([]*Record, 0, t.count)
+	if t.root != nil {
+		for node := leftmost(t.root); node != nil; node = successor(node) {
+			records = append(records, node.record())
+		}
+	}
+	return NewSSTable(tableName, records)
+}
+
+// NewIterator returns an iterator over the tree, which steps between neighbouring nodes by following parent links.
+// The tree must not be modified while the iterator is in use.
+func (t *RedBlackTree) NewIterator() Iterator {
+	return &rbIterator{tree: t}
+}
+
+type rbIterator struct {
+	tree    *RedBlackTree
+	current *rbNode
+}
+
+// skipDeleted moves past any tombstones in the given direction.
+func (it *rbIterator) skipDeleted(forward bool) bool {
+	for it.current != nil && it.current.kind == KindDelete {
+		if forward {
+			it.current = successor(it.current)
+		} else {
+			it.current = predecessor(it.current)
+		}
+	}
+	return it.current != nil
+}
+
+func (it *rbIterator) SeekGE(key []byte) bool {
+	var candidate *rbNode
+	node := it.tree.root
+	for node != nil {
+		if bytes.Compare(node.key, key) >= 0 {
+			candidate = node
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	it.current = candidate
+	return it.skipDeleted(true)
+}
+
+func (it *rbIterator) SeekLT(key []byte) bool {
+	var candidate *rbNode
+	node := it.tree.root
+	for node != nil {
+		if bytes.Compare(node.key, key) < 0 {
+			candidate = node
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+	it.current = candidate
+	return it.skipDeleted(false)
+}
+
+func (it *rbIterator) First() bool {
+	it.current = it.tree.root
+	if it.current != nil {
+		it.current = leftmost(it.current)
+	}
+	return it.skipDeleted(true)
+}
+
+func (it *rbIterator) Last() bool {
+	it.current = it.tree.root
+	if it.current != nil {
+		it.current = rightmost(it.current)
+	}
+	return it.skipDeleted(false)
+}
+
+func (it *rbIterator) Next() bool {
+	if it.current == nil {
+		return false
+	}
+	it.current = successor(it.current)
+	return it.skipDeleted(true)
+}
+
+func (it *rbIterator) Prev() bool {
+	if it.current == nil {
+		return false
+	}
+	it.current = predecessor(it.current)
+	return it.skipDeleted(false)
+}
+
+// successor returns the node following node in key order, or nil if it is the last.
+func successor(node *rbNode) *rbNode {
+	if node.right != nil {
+		return leftmost(node.right)
+	}
+	for node.parent != nil && node == node.parent.right {
+		node = node.parent
+	}
+	return node.parent
+}
+
+// predecessor returns the node preceding node in key order, or nil if it is the first.
+func predecessor(node *rbNode) *rbNode {
+	if node.left != nil {
+		return rightmost(node.left)
+	}
+	for node.parent != nil && node == node.parent.left {
+		node = node.parent
+	}
+	return node.parent
+}
+
+func leftmost(node *rbNode) *rbNode {
+	for node.left != nil {
+		node = node.left
+	}
+	return node
+}
+
+func rightmost(node *rbNode) *rbNode {
+	for node.right != nil {
+		node = node.right
+	}
+	return node
+}
+
+func (it *rbIterator) Key() []byte {
+	return it.current.key
+}
+
+func (it *rbIterator) Value() []byte {
+	return it.current.value
+}
+
+func (it *rbIterator) Record() *Record {
+	return NewRecordWithCount(it.current.key, it.current.value, it.current.atomicCount)
+}
+
+func (it *rbIterator) Error() error {
+	return nil
+}
+
+func (it *rbIterator) Close() error {
+	it.current = nil
+	return nil
+}
@@ -0,0 +1,89 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"testing"
+)
+
+// checkRedBlack verifies the ordering, parent links and red-black properties of the subtree rooted at node,
+// returning its black height.
+func checkRedBlack(t *testing.T, node *rbNode) int {
+	if node == nil {
+		return 1
+	}
+	for _, child := range []*rbNode{node.left, node.right} {
+		if child == nil {
+			continue
+		}
+		require.Same(t, node, child.parent)
+		require.False(t, node.red && child.red, "red node %q has a red child", node.key)
+	}
+	if node.left != nil {
+		require.Negative(t, bytes.Compare(node.left.key, node.key))
+	}
+	if node.right != nil {
+		require.Positive(t, bytes.Compare(node.right.key, node.key))
+	}
+	leftHeight := checkRedBlack(t, node.left)
+	require.Equal(t, leftHeight, checkRedBlack(t, node.right), "unequal black heights below %q", node.key)
+	if node.red {
+		return leftHeight
+	}
+	return leftHeight + 1
+}
+
+func TestRedBlackTree_Balanced(t *testing.T) {
+	orders := map[string][]int{
+		"sequential": make([]int, 1000),
+		"reverse":    make([]int, 1000),
+		"random":     rand.New(rand.NewSource(1)).Perm(1000),
+	}
+	for i := range orders["sequential"] {
+		orders["sequential"][i] = i
+		orders["reverse"][i] = 999 - i
+	}
+
+	for name, order := range orders {
+		t.Run(name, func(t *testing.T) {
+			tree := NewRedBlackTree()
+			for _, i := range order {
+				tree.Insert([]byte(fmt.Sprintf("key-%04d", i)), []byte("value"), uint64(i))
+			}
+			require.False(t, tree.root.red)
+			require.Nil(t, tree.root.parent)
+			// a red-black tree with n nodes has a black height of at most log2(n+1)
+			assert.LessOrEqual(t, checkRedBlack(t, tree.root), 11)
+			assert.Equal(t, 1000, tree.Len())
+
+			results, err := tree.Scan()
+			require.NoError(t, err)
+			require.Len(t, results, 1000)
+			for i, rec := range results {
+				assert.Equal(t, []byte(fmt.Sprintf("key-%04d", i)), rec.Key)
+			}
+		})
+	}
+}
+
+func TestRedBlackTree_Insert(t *testing.T) {
+	tree := NewRedBlackTree()
+	tree.Insert([]byte("Hello"), []byte("World"), 2)
+	tree.Insert([]byte("Hello"), []byte("Stale"), 1)
+	rec, err := tree.Get([]byte("Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("World"), rec.Value)
+
+	tree.Insert([]byte("Hello"), []byte("Newer"), 3)
+	rec, err = tree.Get([]byte("Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Newer"), rec.Value)
+	assert.Equal(t, uint64(3), rec.AtomicCount)
+	assert.Equal(t, 1, tree.Len())
+
+	table := tree.ToSSTable("ExampleTest")
+	assert.Equal(t, uint64(1), table.Metadata.KeyCount)
+}
@@ -2,12 +2,40 @@ package sstable
 
 import (
 	"bytes"
-	"os"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 )
 
-// TODO - Update search to check for tombstone value
+// RecordKind identifies what a record represents, allowing deletions to be stored without reserving any value.
+type RecordKind uint8
+
+const (
+	KindPut RecordKind = iota
+	KindDelete
+)
+
+// valid reports whether k is a kind this version knows how to read.
+func (k RecordKind) valid() bool {
+	return k <= KindDelete
+}
+
+// TombstoneMarker is the value which marked a deleted key before records carried a kind.
+//
+// Deprecated: deletions are now records of KindDelete, created with NewTombstone, and a value equal to
+// TombstoneMarker is an ordinary live value. The variable will be removed in a future release.
 var TombstoneMarker = []byte("#DELETED#")
 
+const (
+	// MaxKeySize is the largest key a record can hold, as key sizes are stored as uint32.
+	MaxKeySize = math.MaxUint32
+	// MaxValueSize is the largest value a record can hold, as value sizes are stored as uint32.
+	MaxValueSize = math.MaxUint32
+)
+
+var ErrRecordTooLarge = errors.New("sstable: record exceeds maximum key or value size")
+
 type Records []*Record
 
 func (r Records) Len() int {
@@ -22,6 +50,10 @@ func (r Records) Swap(i, j int) {
 	r[i], r[j] = r[j], r[i]
 }
 
+// ToBytes concatenates the standalone encoding of each record, as produced by Record.ToBytes.
+//
+// Deprecated: tables store records in data blocks, which this layout is not part of. Use SSTable.ToBytes to encode
+// a table.
 func (r Records) ToBytes() ([]byte, error) {
 	allRecords := [][]byte{}
 	for _, rec := range r {
@@ -34,15 +66,22 @@ func (r Records) ToBytes() ([]byte, error) {
 	return bytes.Join(allRecords, nil), nil
 }
 
-func (r Records) Size() int {
-	size := 0
+// Size returns the length of the encoding returned by ToBytes.
+//
+// Deprecated: this is not the size of the records within a table, use SSTable.Size for that.
+func (r Records) Size() uint64 {
+	var size uint64
 	for _, rec := range r {
 		size += rec.Size()
 	}
 	return size
 }
 
+// Record is a single key and value, or a tombstone, along with the atomic count ordering it against other writes
+// of the same key. KeySize and ValueSize only describe keys and values of up to MaxKeySize and MaxValueSize bytes,
+// larger records are rejected with ErrRecordTooLarge when they are written.
 type Record struct {
+	Kind        RecordKind
 	KeySize     uint32
 	Key         []byte
 	ValueSize   uint32
@@ -69,95 +108,165 @@ func NewRecord(key, value []byte, countFunc AtomicCounter) *Record {
 	return r
 }
 
+func NewTombstoneWithCount(key []byte, count uint64) *Record {
+	return NewTombstone(key, func() uint64 {
+		return count
+	})
+}
+
+// NewTombstone creates a record marking the key as deleted, shadowing any older value for the key.
+func NewTombstone(key []byte, countFunc AtomicCounter) *Record {
+	r := NewRecord(key, nil, countFunc)
+	r.Kind = KindDelete
+	return r
+}
+
 func (r *Record) Deleted() bool {
-	return bytes.Compare(r.Value, TombstoneMarker) == 0
+	return r.Kind == KindDelete
 }
 
+// checkSize returns ErrRecordTooLarge if the key or value is too large to be written.
+func (r *Record) checkSize() error {
+	if uint64(len(r.Key)) > MaxKeySize || uint64(len(r.Value)) > MaxValueSize {
+		return fmt.Errorf("%w: key of %d bytes, value of %d bytes", ErrRecordTooLarge, len(r.Key), len(r.Value))
+	}
+	return nil
+}
+
+// ToBytes encodes the record on its own as kind | key size | key | value size | value | atomic count | checksum.
+//
+// Deprecated: this standalone layout is not how records are stored within a table's blocks, and tables can not be
+// read by decoding it. Use SSTable.ToBytes to encode a table.
 func (r *Record) ToBytes() ([]byte, error) {
+	if err := r.checkSize(); err != nil {
+		return nil, err
+	}
 	contents := make([]byte, r.Size())
 
-	byteOrdering.PutUint32(contents, r.KeySize)
-	copy(contents[4:], r.Key)
-	offset := 4 + len(r.Key)
+	contents[0] = byte(r.Kind)
+	byteOrdering.PutUint32(contents[1:], r.KeySize)
+	copy(contents[5:], r.Key)
+	offset := 5 + len(r.Key)
 	byteOrdering.PutUint32(contents[offset:], r.ValueSize)
 	offset += 4
 	copy(contents[offset:], r.Value)
 	offset += len(r.Value)
 	byteOrdering.PutUint64(contents[offset:], r.AtomicCount)
+	offset += 8
+	byteOrdering.PutUint32(contents[offset:], checksum(contents[:offset]))
 	return contents, nil
 }
 
-func RecordFromBytes(contents []byte) *Record {
+// RecordFromBytes decodes a record written by Record.ToBytes, verifying its checksum. Returned slices alias contents.
+//
+// Deprecated: it does not read records from a table's blocks. Use DiskTable to read tables.
+func RecordFromBytes(contents []byte) (*Record, error) {
 	r := &Record{}
 
-	r.KeySize = byteOrdering.Uint32(contents)
-	offset := 4
+	if len(contents) < 5 {
+		return nil, newCorruptionError(0, "record header is truncated")
+	}
+	r.Kind = RecordKind(contents[0])
+	if !r.Kind.valid() {
+		return nil, newCorruptionError(0, "record kind is unknown")
+	}
+	r.KeySize = byteOrdering.Uint32(contents[1:])
+	offset := 5
+	if uint64(offset)+uint64(r.KeySize)+4 > uint64(len(contents)) {
+		return nil, newCorruptionError(int64(offset), "record key is truncated")
+	}
 	r.Key = contents[offset : offset+int(r.KeySize)]
 	offset += int(r.KeySize)
 	r.ValueSize = byteOrdering.Uint32(contents[offset:])
 	offset += 4
+	if uint64(offset)+uint64(r.ValueSize)+12 > uint64(len(contents)) {
+		return nil, newCorruptionError(int64(offset), "record value is truncated")
+	}
 	r.Value = contents[offset : offset+int(r.ValueSize)]
 	offset += int(r.ValueSize)
 	r.AtomicCount = byteOrdering.Uint64(contents[offset:])
+	offset += 8
 
-	return r
+	if byteOrdering.Uint32(contents[offset:]) != checksum(contents[:offset]) {
+		return nil, newCorruptionError(0, "record checksum mismatch")
+	}
+	return r, nil
 }
 
-func (r *Record) Size() int {
-	return 4 + int(r.KeySize) + 4 + int(r.ValueSize) + 8
+// Size returns the length of the record's encoding by ToBytes.
+func (r *Record) Size() uint64 {
+	return 1 + 4 + uint64(r.KeySize) + 4 + uint64(r.ValueSize) + 8 + 4
 }
 
-func KeyFromDisk(r *os.File, offset int64) (*Record, error) {
-	keySizBytes := make([]byte, 4)
-	_, err := r.Seek(offset, 0)
-	if err != nil {
+// KeyFromDisk reads only the kind and key of a record written by Record.ToBytes at offset from r, which holds size
+// bytes, returning a CorruptionError if the key runs past the end of r. Like RecordFromDisk it only uses positional
+// reads, so it is safe to call concurrently on the same file.
+//
+// Deprecated: it does not read records from a table's blocks. Use DiskTable or NewDiskTableFromReader to read tables.
+func KeyFromDisk(r io.ReaderAt, size int64, offset int64) (*Record, error) {
+	headerBytes := make([]byte, 5)
+	if err := readFullAt(r, headerBytes, offset); err != nil {
 		return nil, err
 	}
-	if _, err := r.Read(keySizBytes); err != nil {
-		return nil, err
+	keySize := byteOrdering.Uint32(headerBytes[1:])
+	if offset+5+int64(keySize) > size {
+		return nil, &CorruptionError{File: readerName(r), Offset: offset, Reason: "record key runs past end of file"}
 	}
-	offset += 4
-	keySize := byteOrdering.Uint32(keySizBytes)
 	key := make([]byte, keySize)
-	if _, err := r.ReadAt(key, offset); err != nil {
+	if err := readFullAt(r, key, offset+5); err != nil {
 		return nil, err
 	}
-	return &Record{KeySize: keySize, Key: key}, nil
+	return &Record{Kind: RecordKind(headerBytes[0]), KeySize: keySize, Key: key}, nil
 }
 
-func RecordFromDisk(r *os.File, offset int64) (*Record, error) {
-	rec := &Record{}
-	keySizBytes := make([]byte, 4)
-	_, err := r.Seek(offset, 0)
-	if err != nil {
+// RecordFromDisk reads a record written by Record.ToBytes at offset from r, which holds size bytes, returning a
+// CorruptionError if the record runs past the end of r or its checksum does not match.
+//
+// Deprecated: it does not read records from a table's blocks. Use DiskTable or NewDiskTableFromReader to read tables.
+func RecordFromDisk(r io.ReaderAt, size int64, offset int64) (*Record, error) {
+	headerBytes := make([]byte, 5)
+	if err := readFullAt(r, headerBytes, offset); err != nil {
 		return nil, err
 	}
-	if _, err := r.Read(keySizBytes); err != nil {
-		return nil, err
-	}
-	offset += 4
-	rec.KeySize = byteOrdering.Uint32(keySizBytes)
-	rec.Key = make([]byte, rec.KeySize)
-	if _, err := r.ReadAt(rec.Key, offset); err != nil {
-		return nil, err
+	name := readerName(r)
+	keySize := int64(byteOrdering.Uint32(headerBytes[1:]))
+	if offset+5+keySize+4 > size {
+		return nil, &CorruptionError{File: name, Offset: offset, Reason: "record key runs past end of file"}
 	}
-	offset += int64(rec.KeySize)
 	valueSizeBytes := make([]byte, 4)
-	if _, err := r.ReadAt(valueSizeBytes, offset); err != nil {
+	if err := readFullAt(r, valueSizeBytes, offset+5+keySize); err != nil {
 		return nil, err
 	}
-	offset += 4
-	rec.ValueSize = byteOrdering.Uint32(valueSizeBytes)
-	rec.Value = make([]byte, rec.ValueSize)
-	if _, err := r.ReadAt(rec.Value, offset); err != nil {
-		return nil, err
+	recordSize := 5 + keySize + 4 + int64(byteOrdering.Uint32(valueSizeBytes)) + 8 + 4
+	if offset+recordSize > size {
+		return nil, &CorruptionError{File: name, Offset: offset, Reason: "record value runs past end of file"}
 	}
-	offset += int64(rec.ValueSize)
 
-	atomicCountBytes := make([]byte, 8)
-	if _, err := r.ReadAt(atomicCountBytes, offset); err != nil {
+	contents := make([]byte, recordSize)
+	if err := readFullAt(r, contents, offset); err != nil {
 		return nil, err
 	}
-	rec.AtomicCount = byteOrdering.Uint64(atomicCountBytes)
+	rec, err := RecordFromBytes(contents)
+	if err != nil {
+		return nil, annotateCorruption(err, name, offset)
+	}
 	return rec, nil
 }
+
+// readFullAt fills p from r at offset. ReaderAt implementations may return io.EOF alongside a full read which ends
+// at the end of the input, which is not an error here.
+func readFullAt(r io.ReaderAt, p []byte, offset int64) error {
+	n, err := r.ReadAt(p, offset)
+	if err == io.EOF && n == len(p) {
+		return nil
+	}
+	return err
+}
+
+// readerName returns the name of readers which have one, such as files, for use in errors.
+func readerName(r io.ReaderAt) string {
+	if named, ok := r.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
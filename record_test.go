@@ -1,8 +1,10 @@
 package sstable
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"os"
 	"testing"
 )
@@ -36,8 +38,14 @@ func TestRecord_ToBytes(t *testing.T) {
 	byteRecord, err := record.ToBytes()
 	assert.NoError(t, err)
 
-	otherRecord := RecordFromBytes(byteRecord)
+	otherRecord, err := RecordFromBytes(byteRecord)
+	assert.NoError(t, err)
 	assert.Equal(t, record, otherRecord)
+
+	byteRecord[6] ^= 0x01
+	_, err = RecordFromBytes(byteRecord)
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
 }
 
 func TestKeyFromDisk(t *testing.T) {
@@ -59,10 +67,16 @@ func TestKeyFromDisk(t *testing.T) {
 	_, err = file.Write(contents)
 	require.NoError(t, err)
 
-	res, err := KeyFromDisk(file, 0)
+	res, err := KeyFromDisk(file, int64(len(contents)), 0)
+	require.NoError(t, err)
 	assert.Equal(t, uint32(5), res.KeySize)
 	assert.Equal(t, []byte("Hello"), res.Key)
-	assert.NoError(t, err)
+
+	// a corrupt key size is rejected before anything is allocated for the key
+	byteOrdering.PutUint32(contents[1:], 1<<31)
+	_, err = KeyFromDisk(bytes.NewReader(contents), int64(len(contents)), 0)
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
 }
 
 func TestRecordFromDisk(t *testing.T) {
@@ -82,7 +96,7 @@ func TestRecordFromDisk(t *testing.T) {
 	_, err = file.Write(contents)
 	require.NoError(t, err)
 
-	res, err := RecordFromDisk(file, 0)
+	res, err := RecordFromDisk(file, int64(len(contents)), 0)
 	assert.Equal(t, uint32(5), res.KeySize)
 	assert.Equal(t, []byte("Hello"), res.Key)
 	assert.Equal(t, uint32(5), res.ValueSize)
@@ -96,23 +110,31 @@ func TestRecord_Deleted(t *testing.T) {
 	testCases := []struct {
 		Name          string
 		ExpectDeleted bool
-		Value         []byte
+		Record        *Record
 	}{
 		{
 			Name:          "not deleted",
 			ExpectDeleted: false,
-			Value:         []byte("Привет всем"),
+			Record:        NewRecordWithCount([]byte("Hello"), []byte("Привет всем"), 1),
+		},
+		{
+			Name:          "legacy marker is a live value",
+			ExpectDeleted: false,
+			Record:        NewRecordWithCount([]byte("Hello"), []byte("#DELETED#"), 1),
 		},
 		{
 			Name:          "deleted",
 			ExpectDeleted: true,
-			Value:         TombstoneMarker,
+			Record:        NewTombstoneWithCount([]byte("Hello"), 1),
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			record := &Record{Value: tc.Value}
+			contents, err := tc.Record.ToBytes()
+			require.NoError(t, err)
+			record, err := RecordFromBytes(contents)
+			require.NoError(t, err)
 
 			if tc.ExpectDeleted {
 				assert.True(t, record.Deleted())
@@ -122,3 +144,66 @@ func TestRecord_Deleted(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordFromDisk_Reader(t *testing.T) {
+	record := NewRecordWithCount([]byte("Hello"), []byte("World"), 3)
+	contents, err := record.ToBytes()
+	require.NoError(t, err)
+
+	res, err := RecordFromDisk(bytes.NewReader(contents), int64(len(contents)), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("World"), res.Value)
+
+	_, err = RecordFromDisk(bytes.NewReader(contents), int64(len(contents))-1, 0)
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
+
+	// a full read which reaches the end of the reader may come with io.EOF
+	res, err = RecordFromDisk(eofReaderAt(contents), int64(len(contents)), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("World"), res.Value)
+	res, err = KeyFromDisk(eofReaderAt(contents[:5+len("Hello")]), int64(len(contents)), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Hello"), res.Key)
+}
+
+// eofReaderAt is a ReaderAt which returns io.EOF along with any read that reaches the end of its contents, as the
+// io.ReaderAt contract allows.
+type eofReaderAt []byte
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(r)) {
+		return 0, io.EOF
+	}
+	n := copy(p, r[off:])
+	if off+int64(n) == int64(len(r)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestRecordFromBytes_UnknownKind(t *testing.T) {
+	contents, err := NewRecordWithCount([]byte("Hello"), []byte("World"), 1).ToBytes()
+	require.NoError(t, err)
+	contents[0] = byte(KindDelete + 1)
+	byteOrdering.PutUint32(contents[len(contents)-4:], checksum(contents[:len(contents)-4]))
+
+	_, err = RecordFromBytes(contents)
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
+}
+
+func TestRecord_SizeOfLargestRecord(t *testing.T) {
+	rec := &Record{KeySize: MaxKeySize, ValueSize: MaxValueSize}
+	assert.Equal(t, uint64(MaxKeySize)+uint64(MaxValueSize)+21, rec.Size())
+}
+
+func TestRecordFromBytes_Truncated(t *testing.T) {
+	contents, err := NewRecordWithCount([]byte("Hello"), []byte("World"), 1).ToBytes()
+	require.NoError(t, err)
+
+	for i := 0; i < len(contents); i++ {
+		_, err := RecordFromBytes(contents[:i])
+		assert.ErrorIs(t, err, ErrCorrupt)
+	}
+}
@@ -1,15 +1,38 @@
 package sstable
 
+import "math"
+
 type Limit struct {
 	MaxResults uint64
 }
 
+// limitValue returns the maximum number of results permitted by limit, a nil limit permits any number.
+func limitValue(limit *Limit) int {
+	if limit == nil || limit.MaxResults > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(limit.MaxResults)
+}
+
 type Predicate func(key, value []byte) bool
 
+// Searcher is implemented by every table and memtable. Deleted keys are treated as absent: Contains reports them as
+// missing, Get returns ErrDeleted, which matches ErrNotFound, for them and scans skip them.
 type Searcher interface {
 	Contains(key []byte) (bool, error)
+	// Get returns the live record with the given key. It returns ErrDeleted if the key's newest write is a
+	// tombstone, and ErrNotFound if the key is not held at all.
 	Get(key []byte) (*Record, error)
 	Scan() ([]*Record, error)
 	ScanWithLimit(limit *Limit) ([]*Record, error)
 	ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error)
+	// ReverseScanWithLimit returns up to limit live records in descending key order, starting from the last key.
+	ReverseScanWithLimit(limit *Limit) ([]*Record, error)
+	// ReverseScanWithPredicate returns up to limit live records matching pred in descending key order.
+	ReverseScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error)
+	// ScanRange returns the live records within the range in ascending key order.
+	ScanRange(r *KeyRange, limit *Limit) ([]*Record, error)
+	// ReverseScanRange returns the live records within the range in descending key order.
+	ReverseScanRange(r *KeyRange, limit *Limit) ([]*Record, error)
+	NewIterator() Iterator
 }
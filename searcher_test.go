@@ -0,0 +1,35 @@
+package sstable
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearcher_Get(t *testing.T) {
+	for name, searcher := range iteratorTestSearchers(t) {
+		t.Run(name, func(t *testing.T) {
+			rec, err := searcher.Get([]byte("key-0042"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("key-0042"), rec.Value)
+
+			// odd keys are deleted
+			_, err = searcher.Get([]byte("key-0043"))
+			assert.ErrorIs(t, err, ErrDeleted)
+			for _, key := range []string{"key-0043", "key-9999", ""} {
+				_, err := searcher.Get([]byte(key))
+				assert.ErrorIs(t, err, ErrNotFound)
+				if key != "key-0043" {
+					assert.NotErrorIs(t, err, ErrDeleted)
+				}
+				found, err := searcher.Contains([]byte(key))
+				assert.NoError(t, err)
+				assert.False(t, found)
+			}
+
+			results, err := searcher.ScanWithLimit(nil)
+			require.NoError(t, err)
+			assert.Len(t, results, 100)
+		})
+	}
+}
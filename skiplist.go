@@ -0,0 +1,353 @@
+package sstable
+
+import (
+	"bytes"
+	"math/rand"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	skipListMaxHeight = 20
+	// skipListBranching is the inverse of the probability that a node reaches each level above the first
+	skipListBranching = 4
+)
+
+// SkipList is a memtable which is safe for concurrent use by any number of goroutines without locking. Inserts
+// link new nodes into each level with compare and swap, and readers never block writers or each other. Nodes are
+// never removed, so a reader sees every insert which completed before its lookup began.
+//
+// Writing a key which is already present keeps whichever write has the higher atomic count, even when writers race
+// to update the same key, so a delete only shadows inserts older than it.
+type SkipList struct {
+	head           *skipNode
+	height         atomic.Int32
+	count          atomic.Int64
+	size           atomic.Int64
+	flushThreshold int64
+}
+
+type skipNode struct {
+	key []byte
+	// entry is replaced as a whole so readers always see a value together with its atomic count
+	entry atomic.Pointer[skipEntry]
+	next  []atomic.Pointer[skipNode]
+}
+
+type skipEntry struct {
+	kind        RecordKind
+	value       []byte
+	atomicCount uint64
+}
+
+// skipNodeOverhead and skipEntryOverhead are the memory a node and its entry take up besides the key, value and
+// the links to following nodes, which take up skipLinkSize for each level of the node.
+const (
+	skipNodeOverhead  = int64(unsafe.Sizeof(skipNode{}))
+	skipEntryOverhead = int64(unsafe.Sizeof(skipEntry{}))
+	skipLinkSize      = int64(unsafe.Sizeof(atomic.Pointer[skipNode]{}))
+)
+
+func NewSkipList() *SkipList {
+	return NewSkipListWithOptions(DefaultMemtableOptions())
+}
+
+// NewSkipListWithOptions creates an empty list which signals a flush according to opts. A nil opts is the same as
+// DefaultMemtableOptions.
+func NewSkipListWithOptions(opts *MemtableOptions) *SkipList {
+	if opts == nil {
+		opts = DefaultMemtableOptions()
+	}
+	s := &SkipList{
+		head:           &skipNode{next: make([]atomic.Pointer[skipNode], skipListMaxHeight)},
+		flushThreshold: opts.FlushThreshold,
+	}
+	s.height.Store(1)
+	return s
+}
+
+// Len returns the number of distinct keys in the list, including deleted keys.
+func (s *SkipList) Len() int {
+	return int(s.count.Load())
+}
+
+func (s *SkipList) ApproximateSize() int64 {
+	return s.size.Load()
+}
+
+func (s *SkipList) ShouldFlush() bool {
+	return shouldFlush(s.size.Load(), s.flushThreshold)
+}
+
+func randomHeight() int {
+	height := 1
+	for height < skipListMaxHeight && rand.Uint32()%skipListBranching == 0 {
+		height++
+	}
+	return height
+}
+
+// findSplice returns, for every level, the last node with a key less than key and the node following it. The
+// node following it on the bottom level is the one holding key, if the list contains it.
+func (s *SkipList) findSplice(key []byte, prev, next *[skipListMaxHeight]*skipNode) {
+	node := s.head
+	for level := int(s.height.Load()) - 1; level >= 0; level-- {
+		node, next[level] = s.findSpliceForLevel(key, level, node)
+		prev[level] = node
+	}
+}
+
+func (s *SkipList) findSpliceForLevel(key []byte, level int, start *skipNode) (*skipNode, *skipNode) {
+	prev := start
+	for {
+		next := prev.next[level].Load()
+		if next == nil || bytes.Compare(next.key, key) >= 0 {
+			return prev, next
+		}
+		prev = next
+	}
+}
+
+func (s *SkipList) Insert(key, value []byte, atomicCount uint64) {
+	s.insert(key, &skipEntry{kind: KindPut, value: value, atomicCount: atomicCount})
+}
+
+func (s *SkipList) Delete(key []byte, atomicCount uint64) {
+	s.insert(key, &skipEntry{kind: KindDelete, atomicCount: atomicCount})
+}
+
+func (s *SkipList) insert(key []byte, entry *skipEntry) {
+	var prev, next [skipListMaxHeight]*skipNode
+	for level := range prev {
+		prev[level] = s.head
+	}
+	s.findSplice(key, &prev, &next)
+	if next[0] != nil && bytes.Equal(next[0].key, key) {
+		s.update(next[0], entry)
+		return
+	}
+
+	height := randomHeight()
+	for listHeight := s.height.Load(); int(listHeight) < height; listHeight = s.height.Load() {
+		if s.height.CompareAndSwap(listHeight, int32(height)) {
+			break
+		}
+	}
+	node := &skipNode{key: key, next: make([]atomic.Pointer[skipNode], height)}
+	node.entry.Store(entry)
+
+	// linking the bottom level makes the node visible, the levels above only speed up searches
+	for level := 0; level < height; level++ {
+		for {
+			node.next[level].Store(next[level])
+			if prev[level].next[level].CompareAndSwap(next[level], node) {
+				break
+			}
+			// another insert changed the splice, so search this level again from the previous node
+			prev[level], next[level] = s.findSpliceForLevel(key, level, prev[level])
+			if level == 0 && next[0] != nil && bytes.Equal(next[0].key, key) {
+				// a concurrent insert of the same key won the race to link its node
+				s.update(next[0], entry)
+				return
+			}
+		}
+	}
+	s.count.Add(1)
+	s.size.Add(skipNodeOverhead + skipEntryOverhead + skipLinkSize*int64(height) + int64(len(key)+len(entry.value)))
+}
+
+// update replaces the node's entry unless it already holds one with an atomic count at least as high.
+func (s *SkipList) update(node *skipNode, entry *skipEntry) {
+	for {
+		current := node.entry.Load()
+		if entry.atomicCount <= current.atomicCount {
+			return
+		}
+		if node.entry.CompareAndSwap(current, entry) {
+			s.size.Add(int64(len(entry.value) - len(current.value)))
+			return
+		}
+	}
+}
+
+// seekGE returns the first node with a key greater than or equal to key.
+func (s *SkipList) seekGE(key []byte) *skipNode {
+	node := s.head
+	var next *skipNode
+	for level := int(s.height.Load()) - 1; level >= 0; level-- {
+		node, next = s.findSpliceForLevel(key, level, node)
+	}
+	return next
+}
+
+// seekLT returns the last node with a key less than key, or nil if there is none.
+func (s *SkipList) seekLT(key []byte) *skipNode {
+	node := s.head
+	for level := int(s.height.Load()) - 1; level >= 0; level-- {
+		node, _ = s.findSpliceForLevel(key, level, node)
+	}
+	if node == s.head {
+		return nil
+	}
+	return node
+}
+
+func (s *SkipList) search(key []byte) *skipNode {
+	node := s.seekGE(key)
+	if node == nil || !bytes.Equal(node.key, key) {
+		return nil
+	}
+	return node
+}
+
+func (s *SkipList) Contains(key []byte) (bool, error) {
+	node := s.search(key)
+	return node != nil && node.entry.Load().kind != KindDelete, nil
+}
+
+func (s *SkipList) Get(key []byte) (*Record, error) {
+	node := s.search(key)
+	if node == nil {
+		return nil, ErrNotFound
+	}
+	rec := node.entry.Load().record(node.key)
+	if rec.Deleted() {
+		return nil, ErrDeleted
+	}
+	return rec, nil
+}
+
+func (e *skipEntry) record(key []byte) *Record {
+	rec := NewRecordWithCount(key, e.value, e.atomicCount)
+	rec.Kind = e.kind
+	return rec
+}
+
+func (s *SkipList) Scan() ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, nil, nil, false)
+}
+
+func (s *SkipList) ScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, nil, limit, false)
+}
+
+func (s *SkipList) ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, pred, limit, false)
+}
+
+func (s *SkipList) ReverseScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, nil, limit, true)
+}
+
+func (s *SkipList) ReverseScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, pred, limit, true)
+}
+
+func (s *SkipList) ScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), r, nil, limit, false)
+}
+
+func (s *SkipList) ReverseScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), r, nil, limit, true)
+}
+
+func (s *SkipList) ToSSTable(tableName string) *SSTable {
+	var records []*Record
+	for node := s.head.next[0].Load(); node != nil; node = node.next[0].Load() {
+		records = append(records, node.entry.Load().record(node.key))
+	}
+	return NewSSTable(tableName, records)
+}
+
+// NewIterator returns an iterator over the list. Unlike the tree memtables the list may be inserted into while the
+// iterator is in use, the iterator sees any node linked in ahead of its position. Stepping forwards follows the
+// bottom level, while stepping backwards searches from the top for the preceding key.
+func (s *SkipList) NewIterator() Iterator {
+	return &skipListIterator{list: s}
+}
+
+type skipListIterator struct {
+	list    *SkipList
+	current *skipNode
+	// entry is the current node's entry, loaded once so Value and Record agree
+	entry *skipEntry
+}
+
+// move positions the iterator on node, then steps past any tombstones in the given direction.
+func (it *skipListIterator) move(node *skipNode, forward bool) bool {
+	for ; node != nil; node = it.neighbour(node, forward) {
+		if entry := node.entry.Load(); entry.kind != KindDelete {
+			it.current, it.entry = node, entry
+			return true
+		}
+	}
+	it.current, it.entry = nil, nil
+	return false
+}
+
+func (it *skipListIterator) neighbour(node *skipNode, forward bool) *skipNode {
+	if forward {
+		return node.next[0].Load()
+	}
+	return it.list.seekLT(node.key)
+}
+
+func (it *skipListIterator) SeekGE(key []byte) bool {
+	return it.move(it.list.seekGE(key), true)
+}
+
+func (it *skipListIterator) SeekLT(key []byte) bool {
+	return it.move(it.list.seekLT(key), false)
+}
+
+func (it *skipListIterator) First() bool {
+	return it.move(it.list.head.next[0].Load(), true)
+}
+
+func (it *skipListIterator) Last() bool {
+	node := it.list.head
+	for level := int(it.list.height.Load()) - 1; level >= 0; level-- {
+		for next := node.next[level].Load(); next != nil; next = node.next[level].Load() {
+			node = next
+		}
+	}
+	if node == it.list.head {
+		return it.move(nil, false)
+	}
+	return it.move(node, false)
+}
+
+func (it *skipListIterator) Next() bool {
+	if it.current == nil {
+		return false
+	}
+	return it.move(it.current.next[0].Load(), true)
+}
+
+func (it *skipListIterator) Prev() bool {
+	if it.current == nil {
+		return false
+	}
+	return it.move(it.list.seekLT(it.current.key), false)
+}
+
+func (it *skipListIterator) Key() []byte {
+	return it.current.key
+}
+
+func (it *skipListIterator) Value() []byte {
+	return it.entry.value
+}
+
+func (it *skipListIterator) Record() *Record {
+	return NewRecordWithCount(it.current.key, it.entry.value, it.entry.atomicCount)
+}
+
+func (it *skipListIterator) Error() error {
+	return nil
+}
+
+func (it *skipListIterator) Close() error {
+	it.current, it.entry = nil, nil
+	return nil
+}
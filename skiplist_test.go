@@ -0,0 +1,107 @@
+package sstable
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSkipList_Insert(t *testing.T) {
+	list := NewSkipList()
+	list.Insert([]byte("Hello"), []byte("World"), 2)
+	list.Insert([]byte("Hello"), []byte("Stale"), 1)
+	list.Insert([]byte("Example"), []byte("Value"), 3)
+
+	rec, err := list.Get([]byte("Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("World"), rec.Value)
+	assert.Equal(t, 2, list.Len())
+
+	_, err = list.Get([]byte("Missing"))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	table := list.ToSSTable("ExampleTest")
+	assert.Equal(t, []string{"Example", "Hello"}, recordKeys(table.Records))
+}
+
+func TestSkipList_ConcurrentInsert(t *testing.T) {
+	const writers = 8
+	const keys = 500
+	list := NewSkipList()
+	keyFor := func(w, i int) []byte {
+		return []byte(fmt.Sprintf("key-%04d", (i*7+w*13)%keys))
+	}
+	// the newest insert of each key is the one with the highest atomic count, whichever writer finishes last
+	highest := make(map[string]uint64)
+	for w := 0; w < writers; w++ {
+		for i := 0; i < keys; i++ {
+			key := string(keyFor(w, i))
+			if count := uint64(i*writers + w); count > highest[key] {
+				highest[key] = count
+			}
+		}
+	}
+
+	// every writer inserts every key, each with its own atomic count, while readers scan and look keys up
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				count := uint64(i*writers + w)
+				list.Insert(keyFor(w, i), []byte(fmt.Sprintf("%d", count)), count)
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				results, err := list.Scan()
+				assert.NoError(t, err)
+				for i := 1; i < len(results); i++ {
+					assert.Less(t, string(results[i-1].Key), string(results[i].Key))
+				}
+				if rec, err := list.Get([]byte("key-0000")); err == nil {
+					assert.Equal(t, []byte(fmt.Sprintf("%d", rec.AtomicCount)), rec.Value)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	assert.Equal(t, keys, list.Len())
+	results, err := list.Scan()
+	require.NoError(t, err)
+	require.Len(t, results, keys)
+	for _, rec := range results {
+		assert.Equal(t, highest[string(rec.Key)], rec.AtomicCount)
+		assert.Equal(t, []byte(fmt.Sprintf("%d", rec.AtomicCount)), rec.Value)
+	}
+}
+
+func BenchmarkSkipList_ConcurrentInsert(b *testing.B) {
+	list := NewSkipList()
+	var counter atomic.Uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			count := counter.Add(1)
+			key := []byte(fmt.Sprintf("key-%016d", count*0x9e3779b97f4a7c15%1000003))
+			list.Insert(key, key, count)
+		}
+	})
+}
@@ -1,11 +1,13 @@
 package sstable
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"github.com/google/uuid"
-	"math"
+	"io"
 	"os"
+	"path/filepath"
 	"sort"
 )
 
@@ -13,11 +15,12 @@ import (
 type SSTable struct {
 	Metadata *TableMeta
 	Records  Records
+	Options  *WriteOptions
 }
 
 func (s *SSTable) binarySearch(key []byte) (*Record, error) {
-	var low uint32
-	high := s.Metadata.KeyCount - 1
+	low := 0
+	high := len(s.Records) - 1
 	for low <= high {
 		middle := (low + high) / 2
 
@@ -36,47 +39,58 @@ func (s *SSTable) binarySearch(key []byte) (*Record, error) {
 
 func (s *SSTable) Contains(key []byte) (bool, error) {
 	result, _ := s.binarySearch(key)
-	return result != nil, nil
+	return result != nil && !result.Deleted(), nil
 }
 
 func (s *SSTable) Get(key []byte) (*Record, error) {
-	return s.binarySearch(key)
+	result, _ := s.binarySearch(key)
+	if result == nil {
+		return nil, ErrNotFound
+	}
+	if result.Deleted() {
+		return nil, ErrDeleted
+	}
+	return result, nil
 }
 
 func (s *SSTable) Scan() ([]*Record, error) {
-	return s.Records, nil
+	return scanIterator(s.NewIterator(), nil, nil, nil, false)
 }
 
 func (s *SSTable) ScanWithLimit(limit *Limit) ([]*Record, error) {
-	if limit == nil {
-		return s.Records, nil
-	}
-	return s.Records[:limit.MaxResults], nil
+	return scanIterator(s.NewIterator(), nil, nil, limit, false)
 }
 
 func (s *SSTable) ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
-	limitValue := math.MaxInt
-	if limit != nil {
-		limitValue = int(limit.MaxResults)
-	}
-	results := make([]*Record, 0, s.Metadata.KeyCount)
-	count := 0
-	for _, rec := range s.Records {
+	return scanIterator(s.NewIterator(), nil, pred, limit, false)
+}
 
-		if pred(rec.Key, rec.Value) && count < limitValue {
-			results = append(results, rec)
-			count += 1
-		}
+// NewIterator returns an iterator over the live records of the table.
+func (s *SSTable) NewIterator() Iterator {
+	return newSliceIterator(s.Records)
+}
 
-		if count == limitValue {
-			return results, nil
-		}
-	}
-	return results, nil
+func (s *SSTable) ReverseScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, nil, limit, true)
+}
+
+func (s *SSTable) ReverseScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), nil, pred, limit, true)
+}
+
+func (s *SSTable) ScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), r, nil, limit, false)
+}
+
+func (s *SSTable) ReverseScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(s.NewIterator(), r, nil, limit, true)
 }
 
 type TableNameFunc func(tableName string) string
 
+// SaveToDisk writes the table to a file named by nameFunc, or named after the table with a random suffix when
+// nameFunc is nil. The table is written to a temporary file in the same directory, which is synced and then renamed
+// into place before the directory itself is synced, so after a crash the table either exists in full or not at all.
 func (s *SSTable) SaveToDisk(nameFunc TableNameFunc) error {
 	var filename string
 	if nameFunc == nil {
@@ -86,56 +100,118 @@ func (s *SSTable) SaveToDisk(nameFunc TableNameFunc) error {
 		filename = nameFunc(string(s.Metadata.TableName))
 	}
 
-	f, err := os.Create(filename)
-	defer f.Close()
+	// the temporary file is created with the same permissions os.Create would give the table, subject to the umask
+	dir, base := filepath.Split(filename)
+	tmpName := filepath.Join(dir, fmt.Sprintf("%s.%s.tmp", base, uuid.New().String()))
+	f, err := os.OpenFile(tmpName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
 	if err != nil {
 		return err
 	}
-	contents, err := s.ToBytes()
-	if err != nil {
+	if err := s.writeFile(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
 		return err
 	}
-	if _, err := f.Write(contents); err != nil {
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
 		return err
 	}
-	return nil
+	if err := os.Rename(f.Name(), filename); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return syncDir(dir)
 }
 
-func (s *SSTable) ToBytes() ([]byte, error) {
-	buffer := make([]byte, s.Size())
-	offset := 0
+// writeFile writes the table to f and syncs it to stable storage.
+func (s *SSTable) writeFile(f *os.File) error {
+	w := bufio.NewWriter(f)
+	if err := s.writeTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
 
-	metaBytes, err := s.Metadata.ToBytes()
-	if err != nil {
+// ToBytes lays the table out as it is stored on disk: the data blocks, followed by the filter block, the index
+// block, the metadata block and finally the footer which locates them.
+func (s *SSTable) ToBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.writeTo(&buf); err != nil {
 		return nil, err
 	}
-	copy(buffer[offset:], metaBytes)
-	offset += len(metaBytes)
+	return buf.Bytes(), nil
+}
 
-	recordBytes, err := s.Records.ToBytes()
+// writeTo streams the table to w.
+func (s *SSTable) writeTo(w io.Writer) error {
+	writer, err := NewTableWriter(w, string(s.Metadata.TableName), s.Options)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	for _, rec := range s.Records {
+		if err := writer.Add(rec); err != nil {
+			return err
+		}
 	}
-	copy(buffer[offset:], recordBytes)
-	return buffer, nil
+	_, err = writer.Finish()
+	return err
 }
 
-func (s *SSTable) Size() int {
-	return s.Metadata.Size() + s.Records.Size()
+// Size returns the number of bytes the table would occupy on disk with its current Records and Options. The table
+// is laid out to count its bytes without keeping them, so the size is not cached and an error is returned if the
+// table can not be encoded.
+func (s *SSTable) Size() (int, error) {
+	counter := &countingWriter{w: io.Discard}
+	if err := s.writeTo(counter); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// countingWriter counts the bytes written through it to w.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
 }
 
 func NewSSTable(tableName string, records []*Record) *SSTable {
-	metadata := NewTableMeta(tableName, uint32(len(records)))
-	offset := metadata.Size()
+	return NewSSTableWithOptions(tableName, records, DefaultWriteOptions())
+}
 
+// NewSSTableWithOptions creates a table holding records, which are sorted by key in place, laid out according to
+// opts when it is encoded. A nil opts is the same as DefaultWriteOptions. When records hold more than one write of
+// the same key only the one with the highest atomic count is kept, as a table holds each key once.
+func NewSSTableWithOptions(tableName string, records []*Record, opts *WriteOptions) *SSTable {
 	sort.Sort(Records(records))
-	for idx, record := range records {
-		metadata.Offsets[idx] = uint32(offset)
-		offset += record.Size()
-	}
-
+	records = newestRecords(records)
 	return &SSTable{
-		Metadata: metadata,
+		Metadata: NewTableMeta(tableName, uint64(len(records))),
 		Records:  records,
+		Options:  opts,
+	}
+}
+
+// newestRecords compacts sorted records in place so each key is left with only its highest atomic count.
+func newestRecords(records []*Record) []*Record {
+	kept := 0
+	for _, rec := range records {
+		if kept > 0 && bytes.Equal(records[kept-1].Key, rec.Key) {
+			if rec.AtomicCount > records[kept-1].AtomicCount {
+				records[kept-1] = rec
+			}
+			continue
+		}
+		records[kept] = rec
+		kept++
 	}
+	return records[:kept]
 }
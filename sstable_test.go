@@ -1,8 +1,12 @@
 package sstable
 
 import (
+	"bytes"
 	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -54,3 +58,92 @@ func TestNewSSTable(t *testing.T) {
 	fmt.Println(r)
 	fmt.Println(err)
 }
+
+func TestSSTable_SaveToDisk(t *testing.T) {
+	dir := t.TempDir()
+	testTableName := filepath.Join(dir, "TestSaveToDisk")
+	nameFunc := func(tableName string) string {
+		return testTableName
+	}
+
+	records := []*Record{NewRecordWithCount([]byte("A"), []byte("Alpha"), 1)}
+	require.NoError(t, NewSSTable("ExampleTest", records).SaveToDisk(nameFunc))
+
+	// saving again replaces the existing table
+	records = []*Record{NewRecordWithCount([]byte("A"), []byte("Apple"), 2)}
+	require.NoError(t, NewSSTable("ExampleTest", records).SaveToDisk(nameFunc))
+
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+	defer diskTable.Close()
+	rec, err := diskTable.Get([]byte("A"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Apple"), rec.Value)
+
+	// the table gets the same permissions as any other file created under the process umask
+	created, err := os.Create(filepath.Join(t.TempDir(), "created"))
+	require.NoError(t, err)
+	require.NoError(t, created.Close())
+	createdInfo, err := os.Stat(created.Name())
+	require.NoError(t, err)
+	tableInfo, err := os.Stat(testTableName)
+	require.NoError(t, err)
+	assert.Equal(t, createdInfo.Mode().Perm(), tableInfo.Mode().Perm())
+
+	// a failed save leaves the existing table in place and no temporary files behind
+	table := NewSSTableWithOptions("ExampleTest", records, &WriteOptions{})
+	assert.Error(t, table.SaveToDisk(nameFunc))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "TestSaveToDisk", entries[0].Name())
+}
+
+func TestSSTable_Size(t *testing.T) {
+	records := []*Record{
+		NewRecordWithCount([]byte("A"), []byte("Alpha"), 1),
+		NewRecordWithCount([]byte("B"), []byte("Bravo"), 2),
+	}
+	table := NewSSTable("SizeTest", records)
+	meta := *table.Metadata
+
+	size, err := table.Size()
+	require.NoError(t, err)
+	contents, err := table.ToBytes()
+	require.NoError(t, err)
+	assert.Equal(t, len(contents), size)
+
+	// encoding the table leaves the caller's metadata as it was
+	assert.Equal(t, meta, *table.Metadata)
+
+	// the size follows changes to the records
+	table.Records = append(table.Records, NewRecordWithCount([]byte("C"), []byte("Charlie"), 3))
+	grown, err := table.Size()
+	require.NoError(t, err)
+	assert.Greater(t, grown, size)
+
+	// a table which can not be encoded has no size
+	table.Options = &WriteOptions{}
+	_, err = table.Size()
+	assert.Error(t, err)
+}
+
+func TestNewSSTable_DuplicateKeys(t *testing.T) {
+	table := NewSSTable("ExampleTest", []*Record{
+		NewRecordWithCount([]byte("B"), []byte("Bravo"), 2),
+		NewRecordWithCount([]byte("A"), []byte("Alpha"), 1),
+		NewRecordWithCount([]byte("B"), []byte("Beta"), 5),
+		NewTombstoneWithCount([]byte("B"), 3),
+	})
+	require.Len(t, table.Records, 2)
+	assert.Equal(t, uint64(2), table.Metadata.KeyCount)
+	assert.Equal(t, []byte("Beta"), table.Records[1].Value)
+
+	contents, err := table.ToBytes()
+	require.NoError(t, err)
+	diskTable, err := NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), "memory", nil)
+	require.NoError(t, err)
+	rec, err := diskTable.Get([]byte("B"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Beta"), rec.Value)
+}
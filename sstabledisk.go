@@ -2,77 +2,358 @@ package sstable
 
 import (
 	"bytes"
-	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"os"
+	"sync"
+	"sync/atomic"
 )
 
 // DiskTable provides a way to interact with a file based table. Supporting search operation over the file.
+// Only the sparse index is held in memory, lookups read and search a single data block.
+//
+// A DiskTable is safe for concurrent use by multiple goroutines. Its state is never modified after it is opened
+// and the file is only read with positional reads, which do not share a file offset. Close waits for reads already
+// in progress to finish before releasing the file or mapping. Iterators returned by NewIterator are not safe for
+// concurrent use, each goroutine should create its own.
+//
+// When opened with ReadOptions.BlockCache decoded blocks are kept in the shared cache, and the index and filter
+// are either pinned in the cache for the lifetime of the table or loaded through it like data blocks.
+//
+// Tables can be read from any io.ReaderAt, such as an in-memory buffer or a wrapper decrypting the underlying
+// storage, with NewDiskTableFromReader. NewDiskTable opens a file and reads the table from it.
+//
+// When opened with ReadOptions.UseMmap the file is memory mapped and lookups decode records straight from the
+// mapping, with returned values aliasing it, so they are only valid until Close.
 type DiskTable struct {
-	file      *os.File
+	reader io.ReaderAt
+	// closer is the file opened by NewDiskTable, tables read from a caller's reader leave closing it to them
+	closer io.Closer
+	// name identifies the table in errors
+	name string
+	// data is the memory mapped file when the table was opened with ReadOptions.UseMmap
+	data      []byte
+	size      int64
+	footer    *footer
+	index     []indexEntry
+	filter    bloomFilter
+	opts      *ReadOptions
 	TableMeta *TableMeta
+	// id identifies the table within the block cache
+	id    uint64
+	cache *BlockCache
+	// mu is held for reading by every read of the table, and for writing by Close
+	mu     sync.RWMutex
+	closed bool
 }
 
+var nextTableID atomic.Uint64
+
 func NewDiskTable(fileName string) (*DiskTable, error) {
+	return NewDiskTableWithOptions(fileName, DefaultReadOptions())
+}
+
+// NewDiskTableWithOptions opens the table stored in fileName, read according to opts. A nil opts is the same as
+// DefaultReadOptions.
+func NewDiskTableWithOptions(fileName string, opts *ReadOptions) (*DiskTable, error) {
 
 	file, err := os.Open(fileName)
 	if err != nil {
 		return nil, err
 	}
-
-	var metaSize uint32
-	err = binary.Read(file, byteOrdering, &metaSize)
+	info, err := file.Stat()
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
-
-	metaBytes := make([]byte, metaSize)
-	if _, err := file.ReadAt(metaBytes, 0); err != nil {
+	d, err := openDiskTable(file, info.Size(), fileName, opts)
+	if err != nil {
+		file.Close()
 		return nil, err
 	}
+	d.closer = file
+	return d, nil
+}
 
-	tableMeta := TableMetaFromBytes(metaBytes)
-
-	return &DiskTable{
-		file:      file,
-		TableMeta: tableMeta,
-	}, nil
+// NewDiskTableFromReader reads a table of size bytes from r, with name identifying it in errors. The reader must
+// support concurrent calls to ReadAt for the table to be used concurrently. Closing the table does not close r,
+// which must stay readable until the table is closed. Only readers which are files can be memory mapped. A nil
+// opts is the same as DefaultReadOptions.
+func NewDiskTableFromReader(r io.ReaderAt, size int64, name string, opts *ReadOptions) (*DiskTable, error) {
+	return openDiskTable(r, size, name, opts)
 }
 
-func (d *DiskTable) binarySearch(key []byte) (*Record, error) {
+func openDiskTable(r io.ReaderAt, size int64, name string, opts *ReadOptions) (d *DiskTable, err error) {
+	if opts == nil {
+		opts = DefaultReadOptions()
+	}
+	if size < footerSizeV1 {
+		return nil, fmt.Errorf("%w: %s", ErrNotTable, name)
+	}
 
-	var low uint32
-	high := d.TableMeta.KeyCount - 1
-	for low <= high {
-		middle := (low + high) / 2
+	d = &DiskTable{
+		reader: r,
+		name:   name,
+		opts:   opts,
+		id:     nextTableID.Add(1),
+	}
+	if opts.UseMmap {
+		file, ok := r.(*os.File)
+		if !ok {
+			return nil, fmt.Errorf("sstable: %s cannot be memory mapped as it is not read from a file", name)
+		}
+		if size > math.MaxInt {
+			return nil, fmt.Errorf("sstable: %s is too large to memory map", name)
+		}
+		d.data, err = mmap(file, int(size))
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				munmap(d.data)
+			}
+		}()
+	} else {
+		// blocks of a memory mapped table are already in memory, so only read tables use the cache
+		d.cache = opts.BlockCache
+	}
 
-		offset := d.TableMeta.Offsets[middle]
+	// the footer's size depends on the version it records, so read as much as the largest footer could need
+	tailSize := min(size, footerSize)
+	tail, err := d.readAt(uint64(size-tailSize), uint64(tailSize))
+	if err != nil {
+		return nil, err
+	}
+	d.footer, err = footerFromBytes(tail)
+	if err != nil {
+		if errors.Is(err, ErrNotTable) || errors.Is(err, ErrUnsupportedVersion) {
+			return nil, fmt.Errorf("%w: %s", err, d.name)
+		}
+		return nil, annotateCorruption(err, d.name, size-tailSize)
+	}
+	d.size = size - int64(footerSizeFor(d.footer.Version))
 
-		otherKey, err := KeyFromDisk(d.file, int64(offset))
+	metaBytes, err := d.readBlock(d.footer.Meta)
+	if err != nil {
+		return nil, err
+	}
+	d.TableMeta, err = tableMetaFromBytes(metaBytes, d.footer.Version)
+	if err != nil {
+		return nil, annotateCorruption(err, d.name, int64(d.footer.Meta.Offset))
+	}
+
+	if d.cache != nil && !opts.PinIndexAndFilter {
+		return d, nil
+	}
+	if d.footer.Filter.Size > 0 {
+		d.filter, err = d.readBlock(d.footer.Filter)
 		if err != nil {
 			return nil, err
 		}
-		cmp := bytes.Compare(key, otherKey.Key)
-		if cmp == 0 {
-			return RecordFromDisk(d.file, int64(offset))
+		d.pin(d.footer.Filter, d.filter, int64(len(d.filter)))
+	}
+
+	indexBytes, err := d.readBlock(d.footer.Index)
+	if err != nil {
+		return nil, err
+	}
+	d.index, err = decodeIndex(indexBytes, d.footer.Version)
+	if err != nil {
+		return nil, annotateCorruption(err, d.name, int64(d.footer.Index.Offset))
+	}
+	d.pin(d.footer.Index, d.index, int64(len(indexBytes)))
+	return d, nil
+}
+
+// pin charges a block held by the table for its whole lifetime to the block cache, if there is one, so the cache
+// capacity accounts for it. Close releases pinned blocks along with the rest of the table's cached blocks.
+func (d *DiskTable) pin(handle blockHandle, value any, charge int64) {
+	if d.cache == nil {
+		return
+	}
+	d.cache.insert(cacheKey{tableID: d.id, offset: handle.Offset}, value, charge, true)
+}
+
+// Close releases the file opened by NewDiskTable and, for memory mapped tables, the mapping, once reads already in
+// progress have finished. For memory mapped tables no record obtained from the table may be used after Close, as
+// their values alias the mapping. Operations on a closed table return ErrClosed, as does closing it again.
+func (d *DiskTable) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	d.closed = true
+	if d.cache != nil {
+		d.cache.eraseTable(d.id)
+	}
+
+	var err error
+	if d.data != nil {
+		err = munmap(d.data)
+		d.data = nil
+	}
+	if d.closer != nil {
+		if closeErr := d.closer.Close(); err == nil {
+			err = closeErr
 		}
-		if cmp < 0 {
-			high = middle - 1
-		} else {
-			low = middle + 1
+	}
+	return err
+}
+
+// acquire holds the table open until the matching release, returning ErrClosed if it has already been closed.
+// Every read of the table happens between the two, as records decoded from a memory mapped table read the mapping.
+func (d *DiskTable) acquire() error {
+	d.mu.RLock()
+	if d.closed {
+		d.mu.RUnlock()
+		return ErrClosed
+	}
+	return nil
+}
+
+func (d *DiskTable) release() {
+	d.mu.RUnlock()
+}
+
+// readAt returns size bytes of the file from offset. Memory mapped tables return a slice of the mapping rather
+// than a copy.
+func (d *DiskTable) readAt(offset, size uint64) ([]byte, error) {
+	if d.data != nil {
+		return d.data[offset : offset+size : offset+size], nil
+	}
+	contents := make([]byte, size)
+	if err := readFullAt(d.reader, contents, int64(offset)); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
+// readBlock reads a block, checking the handle lies within the file before allocating, and returns its contents
+// after verifying the checksum and decompressing them.
+func (d *DiskTable) readBlock(handle blockHandle) ([]byte, error) {
+	if handle.Offset > uint64(d.size) || handle.Size > uint64(d.size)-handle.Offset {
+		return nil, &CorruptionError{File: d.name, Offset: int64(handle.Offset), Reason: "block runs past end of file"}
+	}
+	if handle.Size > math.MaxInt {
+		return nil, fmt.Errorf("sstable: block of %d bytes in %s exceeds addressable memory", handle.Size, d.name)
+	}
+	contents, err := d.readAt(handle.Offset, handle.Size)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := unframeBlock(contents)
+	if err != nil {
+		return nil, annotateCorruption(err, d.name, int64(handle.Offset))
+	}
+	return raw, nil
+}
+
+// cachedBlock returns the block at handle after decoding it, consulting the block cache first when the table
+// has one. Decoded blocks are added to the cache, charged with the size of their uncompressed contents.
+func (d *DiskTable) cachedBlock(handle blockHandle, decode func(raw []byte) (any, error)) (any, error) {
+	key := cacheKey{tableID: d.id, offset: handle.Offset}
+	if d.cache != nil {
+		if value, ok := d.cache.get(key); ok {
+			return value, nil
 		}
 	}
-	return nil, nil
+	raw, err := d.readBlock(handle)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decode(raw)
+	if err != nil {
+		return nil, annotateCorruption(err, d.name, int64(handle.Offset))
+	}
+	if d.cache != nil {
+		d.cache.insert(key, value, int64(len(raw)), false)
+	}
+	return value, nil
+}
+
+// loadIndex returns the index, reading it through the block cache when it is not held by the table.
+func (d *DiskTable) loadIndex() ([]indexEntry, error) {
+	if d.index != nil || d.cache == nil || d.opts.PinIndexAndFilter {
+		return d.index, nil
+	}
+	index, err := d.cachedBlock(d.footer.Index, func(raw []byte) (any, error) {
+		return decodeIndex(raw, d.footer.Version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index.([]indexEntry), nil
+}
+
+// loadFilter returns the filter, reading it through the block cache when it is not held by the table. Tables
+// written without a filter return a nil filter.
+func (d *DiskTable) loadFilter() (bloomFilter, error) {
+	if d.filter != nil || d.cache == nil || d.opts.PinIndexAndFilter || d.footer.Filter.Size == 0 {
+		return d.filter, nil
+	}
+	filter, err := d.cachedBlock(d.footer.Filter, func(raw []byte) (any, error) {
+		return bloomFilter(raw), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filter.(bloomFilter), nil
+}
+
+// dataBlock reads and verifies the data block at handle.
+func (d *DiskTable) dataBlock(handle blockHandle) (*block, error) {
+	blk, err := d.cachedBlock(handle, func(raw []byte) (any, error) {
+		return parseBlock(raw)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blk.(*block), nil
+}
+
+func (d *DiskTable) binarySearch(key []byte) (*Record, error) {
+	if err := d.acquire(); err != nil {
+		return nil, err
+	}
+	defer d.release()
+	filter, err := d.loadFilter()
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil && !filter.mayContain(key) {
+		return nil, nil
+	}
+	index, err := d.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	blockIdx := searchIndex(index, key)
+	if blockIdx == len(index) {
+		return nil, nil
+	}
+
+	handle := index[blockIdx].Handle
+	blk, err := d.dataBlock(handle)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := blk.seek(key)
+	if err != nil {
+		return nil, annotateCorruption(err, d.name, int64(handle.Offset))
+	}
+	return rec, nil
 }
 
 func (d *DiskTable) Contains(key []byte) (bool, error) {
 	val, err := d.binarySearch(key)
-	if val.Deleted() {
-		return false, nil
-	}
 	if err != nil {
 		return false, err
 	}
-	return val != nil, nil
+	return val != nil && !val.Deleted(), nil
 }
 
 func (d *DiskTable) Get(key []byte) (*Record, error) {
@@ -81,64 +362,139 @@ func (d *DiskTable) Get(key []byte) (*Record, error) {
 		return nil, err
 	}
 	if val == nil {
-		return nil, nil
+		return nil, ErrNotFound
 	}
 	if val.Deleted() {
-		return nil, nil
+		return nil, ErrDeleted
 	}
 	return val, nil
 }
 
-func (d *DiskTable) Scan() ([]*Record, error) {
-	results := make([]*Record, 0, d.TableMeta.KeyCount)
-	for i := 0; i < int(d.TableMeta.KeyCount); i++ {
-		offset := d.TableMeta.Offsets[i]
-		rec, err := RecordFromDisk(d.file, int64(offset))
+// blockRecords decodes every record in the data block at handle.
+func (d *DiskTable) blockRecords(handle blockHandle) ([]*Record, error) {
+	blk, err := d.dataBlock(handle)
+	if err != nil {
+		return nil, err
+	}
+	records, err := blk.records()
+	if err != nil {
+		return nil, annotateCorruption(err, d.name, int64(handle.Offset))
+	}
+	return records, nil
+}
+
+// scan walks the data blocks in order, collecting live records matching pred until maxResults are found.
+func (d *DiskTable) scan(pred Predicate, maxResults int) ([]*Record, error) {
+	if err := d.acquire(); err != nil {
+		return nil, err
+	}
+	defer d.release()
+	capacity := maxResults
+	if d.TableMeta.KeyCount < uint64(maxResults) {
+		capacity = int(d.TableMeta.KeyCount)
+	}
+	index, err := d.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Record, 0, capacity)
+	for _, entry := range index {
+		records, err := d.blockRecords(entry.Handle)
 		if err != nil {
 			return nil, err
 		}
-		if !rec.Deleted() {
-			results = append(results, rec)
+		for _, rec := range records {
+			if len(results) == maxResults {
+				return results, nil
+			}
+			if !rec.Deleted() && (pred == nil || pred(rec.Key, rec.Value)) {
+				results = append(results, rec)
+			}
 		}
 	}
 	return results, nil
 }
 
+func (d *DiskTable) Scan() ([]*Record, error) {
+	return d.scan(nil, math.MaxInt)
+}
+
 func (d *DiskTable) ScanWithLimit(limit *Limit) ([]*Record, error) {
-	resultSize := min(d.TableMeta.KeyCount, uint32(limit.MaxResults))
-	results := make([]*Record, 0, resultSize)
+	return d.scan(nil, limitValue(limit))
+}
 
-	for i := 0; i < int(d.TableMeta.KeyCount); i++ {
-		if len(results) == int(resultSize) {
-			return results, nil
-		}
-		offset := d.TableMeta.Offsets[i]
-		rec, err := RecordFromDisk(d.file, int64(offset))
-		if err != nil {
-			return nil, err
-		}
-		if !rec.Deleted() {
-			results = append(results, rec)
-		}
+func (d *DiskTable) ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return d.scan(pred, limitValue(limit))
+}
+
+func (d *DiskTable) ReverseScanWithLimit(limit *Limit) ([]*Record, error) {
+	return scanIterator(d.NewIterator(), nil, nil, limit, true)
+}
+
+func (d *DiskTable) ReverseScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
+	return scanIterator(d.NewIterator(), nil, pred, limit, true)
+}
+
+func (d *DiskTable) ScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(d.NewIterator(), r, nil, limit, false)
+}
+
+func (d *DiskTable) ReverseScanRange(r *KeyRange, limit *Limit) ([]*Record, error) {
+	return scanIterator(d.NewIterator(), r, nil, limit, true)
+}
+
+// MayContainPrefix reports whether the table may hold a key starting with prefix. It can only rule a prefix out
+// when the table was written with the same PrefixExtractor as the ReadOptions and prefix is one the extractor
+// produces, otherwise it conservatively returns true.
+func (d *DiskTable) MayContainPrefix(prefix []byte) bool {
+	extractor := d.opts.PrefixExtractor
+	if extractor == nil || string(d.TableMeta.PrefixExtractor) != extractor.Name || !extractor.isPrefix(prefix) {
+		return true
 	}
-	return results, nil
+	if err := d.acquire(); err != nil {
+		return true
+	}
+	defer d.release()
+	filter, err := d.loadFilter()
+	if err != nil || filter == nil {
+		return true
+	}
+	return filter.mayContain(prefix)
 }
 
-func (d *DiskTable) ScanWithPredicate(pred Predicate, limit *Limit) ([]*Record, error) {
-	resultSize := min(d.TableMeta.KeyCount, uint32(limit.MaxResults))
-	results := make([]*Record, 0, resultSize)
+// ScanPrefix returns the live records whose keys start with prefix, in key order. Tables ruled out by the prefix
+// filter are skipped without reading any data blocks, otherwise the scan starts at the first block which could
+// hold the prefix.
+func (d *DiskTable) ScanPrefix(prefix []byte, limit *Limit) ([]*Record, error) {
+	results := []*Record{}
+	if !d.MayContainPrefix(prefix) {
+		return results, nil
+	}
 
-	for i := 0; i < int(d.TableMeta.KeyCount); i++ {
-		if len(results) == int(resultSize) {
-			return results, nil
-		}
-		offset := d.TableMeta.Offsets[i]
-		rec, err := RecordFromDisk(d.file, int64(offset))
+	if err := d.acquire(); err != nil {
+		return nil, err
+	}
+	defer d.release()
+	index, err := d.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	maxResults := limitValue(limit)
+	for _, entry := range index[searchIndex(index, prefix):] {
+		records, err := d.blockRecords(entry.Handle)
 		if err != nil {
 			return nil, err
 		}
-		if !rec.Deleted() && pred(rec.Key, rec.Value) {
-			results = append(results, rec)
+		for _, rec := range records {
+			if bytes.Compare(rec.Key, prefix) < 0 {
+				continue
+			}
+			if !bytes.HasPrefix(rec.Key, prefix) || len(results) == maxResults {
+				return results, nil
+			}
+			if !rec.Deleted() {
+				results = append(results, rec)
+			}
 		}
 	}
 	return results, nil
@@ -149,4 +505,146 @@ func minInt(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
+
+// NewIterator returns an iterator over the live records of the table. Only the data block under the iterator is
+// held in memory.
+func (d *DiskTable) NewIterator() Iterator {
+	if err := d.acquire(); err != nil {
+		return &diskTableIterator{table: d, blockIdx: -1, err: err}
+	}
+	defer d.release()
+	index, err := d.loadIndex()
+	return &diskTableIterator{table: d, index: index, blockIdx: -1, err: err}
+}
+
+// diskTableIterator is a two level iterator, moving through the index to pick data blocks and then through the
+// records of the current block.
+type diskTableIterator struct {
+	table    *DiskTable
+	index    []indexEntry
+	blockIdx int
+	records  []*Record
+	idx      int
+	err      error
+}
+
+func (it *diskTableIterator) loadBlock(blockIdx int) bool {
+	it.records = nil
+	it.blockIdx = blockIdx
+	if it.err != nil || blockIdx < 0 || blockIdx >= len(it.index) {
+		return false
+	}
+	if it.err = it.table.acquire(); it.err != nil {
+		return false
+	}
+	defer it.table.release()
+	it.records, it.err = it.table.blockRecords(it.index[blockIdx].Handle)
+	return it.err == nil
+}
+
+// skipForward moves forward from the current position, across blocks if needed, until it rests on a live record.
+func (it *diskTableIterator) skipForward() bool {
+	for it.err == nil && it.records != nil {
+		if it.idx >= len(it.records) {
+			it.idx = 0
+			it.loadBlock(it.blockIdx + 1)
+			continue
+		}
+		if !it.records[it.idx].Deleted() {
+			return true
+		}
+		it.idx++
+	}
+	return false
+}
+
+// skipBackward moves backward from the current position, across blocks if needed, until it rests on a live record.
+func (it *diskTableIterator) skipBackward() bool {
+	for it.err == nil && it.records != nil {
+		if it.idx < 0 {
+			if it.loadBlock(it.blockIdx - 1) {
+				it.idx = len(it.records) - 1
+			}
+			continue
+		}
+		if !it.records[it.idx].Deleted() {
+			return true
+		}
+		it.idx--
+	}
+	return false
+}
+
+func (it *diskTableIterator) SeekGE(key []byte) bool {
+	if !it.loadBlock(searchIndex(it.index, key)) {
+		return false
+	}
+	it.idx = searchRecords(it.records, key)
+	return it.skipForward()
+}
+
+func (it *diskTableIterator) SeekLT(key []byte) bool {
+	blockIdx := searchIndex(it.index, key)
+	if blockIdx == len(it.index) {
+		return it.Last()
+	}
+	if !it.loadBlock(blockIdx) {
+		return false
+	}
+	it.idx = searchRecords(it.records, key) - 1
+	return it.skipBackward()
+}
+
+func (it *diskTableIterator) First() bool {
+	if !it.loadBlock(0) {
+		return false
+	}
+	it.idx = 0
+	return it.skipForward()
+}
+
+func (it *diskTableIterator) Last() bool {
+	if !it.loadBlock(len(it.index) - 1) {
+		return false
+	}
+	it.idx = len(it.records) - 1
+	return it.skipBackward()
+}
+
+func (it *diskTableIterator) Next() bool {
+	if it.records == nil {
+		return false
+	}
+	it.idx++
+	return it.skipForward()
+}
+
+func (it *diskTableIterator) Prev() bool {
+	if it.records == nil {
+		return false
+	}
+	it.idx--
+	return it.skipBackward()
+}
+
+func (it *diskTableIterator) Key() []byte {
+	return it.records[it.idx].Key
+}
+
+func (it *diskTableIterator) Value() []byte {
+	return it.records[it.idx].Value
+}
+
+func (it *diskTableIterator) Record() *Record {
+	return it.records[it.idx]
+}
+
+func (it *diskTableIterator) Error() error {
+	return it.err
+}
+
+func (it *diskTableIterator) Close() error {
+	it.records = nil
+	return nil
+}
@@ -1,11 +1,19 @@
 package sstable
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"unsafe"
 )
 
 func TestDiskTable_Contains(t *testing.T) {
@@ -96,3 +104,319 @@ func TestDiskTable_Scan(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, allRecords, results)
 }
+
+func TestDiskTable_Corruption(t *testing.T) {
+	testTableName := "TestDiskTableCorruption"
+	records := []*Record{
+		NewRecordWithCount([]byte("A"), []byte("Alpha"), 1),
+		NewRecordWithCount([]byte("B"), []byte("Bravo"), 2),
+	}
+	table := NewSSTable("ExampleTest", records)
+	contents, err := table.ToBytes()
+	require.NoError(t, err)
+
+	contents[5] ^= 0x01
+	require.NoError(t, os.WriteFile(testTableName, contents, 0644))
+	defer os.Remove(testTableName)
+
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+
+	_, err = diskTable.Get([]byte("B"))
+	var corrupt *CorruptionError
+	require.ErrorAs(t, err, &corrupt)
+	assert.Equal(t, testTableName, corrupt.File)
+	// the only data block sits directly before the filter, with its checksum as the final four bytes
+	assert.Equal(t, int64(diskTable.footer.Filter.Offset)-4, corrupt.Offset)
+}
+
+func TestDiskTable_Reader(t *testing.T) {
+	records := []*Record{
+		NewRecordWithCount([]byte("A"), []byte("Alpha"), 1),
+		NewRecordWithCount([]byte("B"), []byte("Bravo"), 2),
+	}
+	contents, err := NewSSTable("ExampleTest", records).ToBytes()
+	require.NoError(t, err)
+
+	diskTable, err := NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), "memory", DefaultReadOptions())
+	require.NoError(t, err)
+	rec, err := diskTable.Get([]byte("B"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Bravo"), rec.Value)
+	assert.NoError(t, diskTable.Close())
+
+	// reads which end at the end of the table may come with io.EOF
+	diskTable, err = NewDiskTableFromReader(eofReaderAt(contents), int64(len(contents)), "memory", DefaultReadOptions())
+	require.NoError(t, err)
+	rec, err = diskTable.Get([]byte("B"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Bravo"), rec.Value)
+	assert.NoError(t, diskTable.Close())
+
+	_, err = NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), "memory", &ReadOptions{UseMmap: true})
+	assert.Error(t, err)
+
+	// the reader only exposes the start of the table, so the footer is not where the table says it is
+	_, err = NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents))-1, "memory", DefaultReadOptions())
+	assert.ErrorIs(t, err, ErrNotTable)
+
+	contents[5] ^= 0x01
+	diskTable, err = NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), "memory", DefaultReadOptions())
+	require.NoError(t, err)
+	_, err = diskTable.Get([]byte("B"))
+	var corrupt *CorruptionError
+	require.ErrorAs(t, err, &corrupt)
+	assert.Equal(t, "memory", corrupt.File)
+}
+
+func TestDiskTable_NilOptions(t *testing.T) {
+	records := []*Record{NewRecordWithCount([]byte("A"), []byte("Alpha"), 1)}
+	contents, err := NewSSTableWithOptions("ExampleTest", records, nil).ToBytes()
+	require.NoError(t, err)
+
+	diskTable, err := NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), "memory", nil)
+	require.NoError(t, err)
+	defer diskTable.Close()
+	rec, err := diskTable.Get([]byte("A"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Alpha"), rec.Value)
+	assert.True(t, diskTable.MayContainPrefix([]byte("A")))
+}
+
+func TestDiskTable_Closed(t *testing.T) {
+	records := []*Record{NewRecordWithCount([]byte("A"), []byte("Alpha"), 1)}
+	contents, err := NewSSTable("ExampleTest", records).ToBytes()
+	require.NoError(t, err)
+	diskTable, err := NewDiskTableFromReader(bytes.NewReader(contents), int64(len(contents)), "memory", DefaultReadOptions())
+	require.NoError(t, err)
+	it := diskTable.NewIterator()
+	defer it.Close()
+
+	require.NoError(t, diskTable.Close())
+	assert.ErrorIs(t, diskTable.Close(), ErrClosed)
+	_, err = diskTable.Get([]byte("A"))
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = diskTable.Contains([]byte("A"))
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = diskTable.Scan()
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.False(t, it.First())
+	assert.ErrorIs(t, it.Error(), ErrClosed)
+}
+
+func TestDiskTable_MultipleBlocks(t *testing.T) {
+	testTableName := "TestDiskTableMultipleBlocks"
+
+	var allRecords []*Record
+	for i := 0; i < 500; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", i*2))
+		allRecords = append(allRecords, NewRecordWithCount(key, []byte(fmt.Sprintf("value-%d", i)), uint64(i)))
+	}
+
+	table := NewSSTableWithOptions("ExampleTest", allRecords, &WriteOptions{BlockSize: 256})
+	err := table.SaveToDisk(func(tableName string) string {
+		return testTableName
+	})
+	require.NoError(t, err)
+	defer os.Remove(testTableName)
+
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+	assert.Greater(t, len(diskTable.index), 1)
+	assert.Equal(t, uint64(500), diskTable.TableMeta.KeyCount)
+
+	for i, rec := range allRecords {
+		found, err := diskTable.Get(rec.Key)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, rec.Value, found.Value)
+
+		missing, err := diskTable.Contains([]byte(fmt.Sprintf("key-%04d", i*2+1)))
+		assert.NoError(t, err)
+		assert.False(t, missing)
+	}
+
+	results, err := diskTable.ScanWithLimit(&Limit{MaxResults: 300})
+	assert.NoError(t, err)
+	assert.Equal(t, allRecords[:300], results)
+}
+
+func TestNewDiskTable_Footer(t *testing.T) {
+	testTableName := "TestNewDiskTableFooter"
+	defer os.Remove(testTableName)
+
+	contents, err := NewSSTable("ExampleTest", []*Record{
+		NewRecordWithCount([]byte("A"), []byte("Alpha"), 1),
+	}).ToBytes()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(testTableName, []byte("definitely not a table, just some text"), 0644))
+	_, err = NewDiskTable(testTableName)
+	assert.ErrorIs(t, err, ErrNotTable)
+
+	newerVersion := &footer{Version: formatVersion + 1}
+	newerContents := append(append([]byte{}, contents[:len(contents)-footerSize]...), newerVersion.ToBytes()...)
+	require.NoError(t, os.WriteFile(testTableName, newerContents, 0644))
+	_, err = NewDiskTable(testTableName)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	contents[len(contents)-footerSize] ^= 0x01
+	require.NoError(t, os.WriteFile(testTableName, contents, 0644))
+	_, err = NewDiskTable(testTableName)
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
+}
+
+func TestDiskTable_ReadBlockOutOfRange(t *testing.T) {
+	testTableName := "TestDiskTableReadBlockOutOfRange"
+	table := NewSSTable("ExampleTest", []*Record{NewRecordWithCount([]byte("A"), []byte("Alpha"), 1)})
+	require.NoError(t, table.SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+	defer os.Remove(testTableName)
+
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+
+	_, err = diskTable.readBlock(blockHandle{Offset: 1 << 40, Size: 16})
+	var corrupt *CorruptionError
+	assert.ErrorAs(t, err, &corrupt)
+
+	_, err = diskTable.readBlock(blockHandle{Offset: 16, Size: math.MaxUint64 - 8})
+	assert.ErrorAs(t, err, &corrupt)
+}
+
+func TestDiskTable_ConcurrentReads(t *testing.T) {
+	testTableName := "TestDiskTableConcurrentReads"
+
+	var allRecords []*Record
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", i))
+		allRecords = append(allRecords, NewRecordWithCount(key, []byte(fmt.Sprintf("value-%d", i)), uint64(i)))
+	}
+	opts := DefaultWriteOptions()
+	opts.BlockSize = 256
+	table := NewSSTableWithOptions("ExampleTest", allRecords, opts)
+	require.NoError(t, table.SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+	defer os.Remove(testTableName)
+
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := worker; i < len(allRecords); i += 8 {
+				rec, err := diskTable.Get(allRecords[i].Key)
+				assert.NoError(t, err)
+				if assert.NotNil(t, rec) {
+					assert.Equal(t, allRecords[i].Value, rec.Value)
+				}
+
+				found, err := diskTable.Contains([]byte(fmt.Sprintf("missing-%04d", i)))
+				assert.NoError(t, err)
+				assert.False(t, found)
+
+				if i%100 == worker {
+					results, err := diskTable.ScanRange(&KeyRange{Start: allRecords[i].Key}, &Limit{MaxResults: 50})
+					assert.NoError(t, err)
+					assert.Equal(t, allRecords[i:minInt(i+50, len(allRecords))], results)
+
+					results, err = diskTable.Scan()
+					assert.NoError(t, err)
+					assert.Equal(t, allRecords, results)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+func TestDiskTable_CloseDuringReads(t *testing.T) {
+	testTableName := filepath.Join(t.TempDir(), "TestDiskTableCloseDuringReads")
+
+	var allRecords []*Record
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", i))
+		allRecords = append(allRecords, NewRecordWithCount(key, []byte(fmt.Sprintf("value-%d", i)), uint64(i)))
+	}
+	opts := DefaultWriteOptions()
+	opts.BlockSize = 256
+	require.NoError(t, NewSSTableWithOptions("ExampleTest", allRecords, opts).SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+
+	for _, useMmap := range []bool{false, true} {
+		t.Run(fmt.Sprintf("mmap=%t", useMmap), func(t *testing.T) {
+			diskTable, err := NewDiskTableWithOptions(testTableName, &ReadOptions{UseMmap: useMmap})
+			if errors.Is(err, ErrMmapUnsupported) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+
+			var wg sync.WaitGroup
+			var reads atomic.Int64
+			for worker := 0; worker < 8; worker++ {
+				wg.Add(1)
+				go func(worker int) {
+					defer wg.Done()
+					for i := worker; ; i = (i + 8) % len(allRecords) {
+						// the record is discarded, as values of a memory mapped table must not be read once Close
+						// may have been called
+						_, err := diskTable.Get(allRecords[i].Key)
+						if errors.Is(err, ErrClosed) {
+							return
+						}
+						assert.NoError(t, err)
+						reads.Add(1)
+					}
+				}(worker)
+			}
+			for reads.Load() < 1000 {
+				runtime.Gosched()
+			}
+			assert.NoError(t, diskTable.Close())
+			wg.Wait()
+		})
+	}
+}
+
+func TestDiskTable_Mmap(t *testing.T) {
+	testTableName := "TestDiskTableMmap"
+
+	var allRecords []*Record
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", i))
+		allRecords = append(allRecords, NewRecordWithCount(key, []byte(fmt.Sprintf("value-%d", i)), uint64(i)))
+	}
+	table := NewSSTable("ExampleTest", allRecords)
+	require.NoError(t, table.SaveToDisk(func(tableName string) string {
+		return testTableName
+	}))
+	defer os.Remove(testTableName)
+
+	diskTable, err := NewDiskTableWithOptions(testTableName, &ReadOptions{UseMmap: true})
+	if errors.Is(err, ErrMmapUnsupported) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	rec, err := diskTable.Get([]byte("key-0042"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-42"), rec.Value)
+
+	mapping := uintptr(unsafe.Pointer(unsafe.SliceData(diskTable.data)))
+	value := uintptr(unsafe.Pointer(unsafe.SliceData(rec.Value)))
+	assert.True(t, value >= mapping && value < mapping+uintptr(len(diskTable.data)), "value should alias the mapping")
+
+	results, err := diskTable.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, allRecords, results)
+
+	assert.NoError(t, diskTable.Close())
+	assert.Nil(t, diskTable.data)
+}
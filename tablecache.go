@@ -0,0 +1,203 @@
+package sstable
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+var ErrTooManyOpenFiles = errors.New("sstable: every open table is in use")
+
+// TableCache opens DiskTables on demand and keeps at most a fixed number of them open. Tables are reference
+// counted while callers use them, and once released are kept open so later lookups can reuse them, until the
+// least recently used idle table has to be closed to make room for another.
+//
+// Tables are opened without holding the cache's lock, so a slow open only delays callers acquiring the same table.
+type TableCache struct {
+	mu           sync.Mutex
+	opts         *ReadOptions
+	maxOpenFiles int
+	tables       map[string]*cachedTable
+	// openFiles counts every open table, including evicted tables which are still in use
+	openFiles int
+	// idle holds the open tables with no references, with the most recently released at the front
+	idle   *list.List
+	closed bool
+}
+
+type cachedTable struct {
+	fileName string
+	// ready is closed once the table has been opened, after which table or err is set
+	ready    chan struct{}
+	table    *DiskTable
+	err      error
+	refs     int
+	idleElem *list.Element
+	// evicted tables are no longer in the cache, and are closed once their last reference is released
+	evicted bool
+}
+
+// TableHandle is a reference to a table acquired from a TableCache. The table stays open until the handle is
+// released.
+type TableHandle struct {
+	Table *DiskTable
+	cache *TableCache
+	entry *cachedTable
+	once  sync.Once
+}
+
+// NewTableCache creates a cache which opens tables with opts and keeps at most maxOpenFiles open at once. A
+// maxOpenFiles of zero or less places no limit on the number of open tables, which are then only closed when
+// evicted.
+func NewTableCache(maxOpenFiles int, opts *ReadOptions) *TableCache {
+	return &TableCache{
+		opts:         opts,
+		maxOpenFiles: maxOpenFiles,
+		tables:       make(map[string]*cachedTable),
+		idle:         list.New(),
+	}
+}
+
+// Acquire returns a handle to the table stored in fileName, opening it if it is not already open. When the cache
+// is full the least recently used idle table is closed, and ErrTooManyOpenFiles is returned if every open table
+// is in use. Callers acquiring a table which another caller is still opening wait for that open to finish and
+// share its result. Every handle must be released once the caller is done with the table. Acquire returns
+// ErrClosed once the cache has been closed.
+func (c *TableCache) Acquire(fileName string) (*TableHandle, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	entry, ok := c.tables[fileName]
+	if !ok {
+		if c.maxOpenFiles > 0 && c.openFiles >= c.maxOpenFiles && !c.evictIdle() {
+			c.mu.Unlock()
+			return nil, ErrTooManyOpenFiles
+		}
+		// the table counts as open while it is being opened, so concurrent opens can not exceed the limit
+		c.openFiles++
+		entry = &cachedTable{fileName: fileName, ready: make(chan struct{}), refs: 1}
+		c.tables[fileName] = entry
+		c.mu.Unlock()
+		return c.open(entry)
+	}
+
+	if entry.idleElem != nil {
+		c.idle.Remove(entry.idleElem)
+		entry.idleElem = nil
+	}
+	entry.refs++
+	c.mu.Unlock()
+
+	<-entry.ready
+	if entry.err != nil {
+		c.mu.Lock()
+		entry.refs--
+		c.mu.Unlock()
+		return nil, entry.err
+	}
+	return &TableHandle{Table: entry.table, cache: c, entry: entry}, nil
+}
+
+// open opens the table of an entry which has just been added to the cache, removing it again if that fails.
+func (c *TableCache) open(entry *cachedTable) (*TableHandle, error) {
+	table, err := NewDiskTableWithOptions(entry.fileName, c.opts)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.table, entry.err = table, err
+	close(entry.ready)
+	if err != nil {
+		entry.refs--
+		c.openFiles--
+		if c.tables[entry.fileName] == entry {
+			delete(c.tables, entry.fileName)
+		}
+		return nil, err
+	}
+	return &TableHandle{Table: table, cache: c, entry: entry}, nil
+}
+
+// Release gives up the handle's reference to the table. Releasing a handle more than once has no effect.
+func (h *TableHandle) Release() {
+	h.once.Do(func() {
+		h.cache.release(h.entry)
+	})
+}
+
+func (c *TableCache) release(entry *cachedTable) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.refs--
+	if entry.refs > 0 {
+		return
+	}
+	if entry.evicted {
+		c.closeTable(entry)
+		return
+	}
+	entry.idleElem = c.idle.PushFront(entry)
+}
+
+// evictIdle closes the least recently used idle table, reporting whether there was one.
+func (c *TableCache) evictIdle() bool {
+	elem := c.idle.Back()
+	if elem == nil {
+		return false
+	}
+	entry := elem.Value.(*cachedTable)
+	c.idle.Remove(elem)
+	delete(c.tables, entry.fileName)
+	c.closeTable(entry)
+	return true
+}
+
+func (c *TableCache) closeTable(entry *cachedTable) {
+	entry.table.Close()
+	c.openFiles--
+}
+
+// Evict removes the table stored in fileName from the cache, for example once the file is about to be deleted.
+// An idle table is closed immediately, otherwise it is closed when its last handle is released.
+func (c *TableCache) Evict(fileName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.tables[fileName]
+	if !ok {
+		return
+	}
+	delete(c.tables, fileName)
+	entry.evicted = true
+	if entry.idleElem != nil {
+		c.idle.Remove(entry.idleElem)
+		entry.idleElem = nil
+		c.closeTable(entry)
+	}
+}
+
+// OpenFiles returns the number of tables the cache currently has open, including any which have been evicted but
+// are still in use.
+func (c *TableCache) OpenFiles() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.openFiles
+}
+
+// Close evicts every table, closing idle tables immediately and the rest as their handles are released. Later
+// calls to Acquire return ErrClosed.
+func (c *TableCache) Close() {
+	c.mu.Lock()
+	c.closed = true
+	fileNames := make([]string, 0, len(c.tables))
+	for fileName := range c.tables {
+		fileNames = append(fileNames, fileName)
+	}
+	c.mu.Unlock()
+
+	for _, fileName := range fileNames {
+		c.Evict(fileName)
+	}
+}
@@ -0,0 +1,112 @@
+package sstable
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestTableCache(t *testing.T) {
+	var fileNames []string
+	for i := 0; i < 3; i++ {
+		testTableName := fmt.Sprintf("TestTableCache%d", i)
+		records := []*Record{NewRecordWithCount([]byte("key"), []byte(testTableName), uint64(i))}
+		require.NoError(t, NewSSTable("ExampleTest", records).SaveToDisk(func(tableName string) string {
+			return testTableName
+		}))
+		defer os.Remove(testTableName)
+		fileNames = append(fileNames, testTableName)
+	}
+
+	cache := NewTableCache(2, DefaultReadOptions())
+	defer cache.Close()
+
+	first, err := cache.Acquire(fileNames[0])
+	require.NoError(t, err)
+	again, err := cache.Acquire(fileNames[0])
+	require.NoError(t, err)
+	assert.Same(t, first.Table, again.Table)
+	second, err := cache.Acquire(fileNames[1])
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.OpenFiles())
+
+	// both open tables are in use so there is no room for a third
+	_, err = cache.Acquire(fileNames[2])
+	assert.ErrorIs(t, err, ErrTooManyOpenFiles)
+
+	first.Release()
+	first.Release()
+	again.Release()
+	third, err := cache.Acquire(fileNames[2])
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.OpenFiles())
+
+	rec, err := third.Table.Get([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte(fileNames[2]), rec.Value)
+
+	// the first table was idle and least recently used, so it was closed to make room
+	_, err = first.Table.closer.(*os.File).Stat()
+	assert.ErrorIs(t, err, os.ErrClosed)
+
+	cache.Evict(fileNames[1])
+	rec, err = second.Table.Get([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte(fileNames[1]), rec.Value)
+	second.Release()
+	assert.Equal(t, 1, cache.OpenFiles())
+
+	third.Release()
+	cache.Close()
+	assert.Equal(t, 0, cache.OpenFiles())
+}
+
+func TestTableCache_ConcurrentAcquire(t *testing.T) {
+	dir := t.TempDir()
+	var fileNames []string
+	for i := 0; i < 4; i++ {
+		testTableName := filepath.Join(dir, fmt.Sprintf("TestTableCacheConcurrent%d", i))
+		records := []*Record{NewRecordWithCount([]byte("key"), []byte(testTableName), uint64(i))}
+		require.NoError(t, NewSSTable("ExampleTest", records).SaveToDisk(func(tableName string) string {
+			return testTableName
+		}))
+		fileNames = append(fileNames, testTableName)
+	}
+
+	// no limit on open files
+	cache := NewTableCache(0, nil)
+	handles := make([]*TableHandle, 32)
+	var wg sync.WaitGroup
+	for i := range handles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			handle, err := cache.Acquire(fileNames[i%len(fileNames)])
+			if assert.NoError(t, err) {
+				handles[i] = handle
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// callers racing to acquire the same table share a single open
+	assert.Equal(t, len(fileNames), cache.OpenFiles())
+	for i, handle := range handles {
+		require.NotNil(t, handle)
+		assert.Same(t, handles[i%len(fileNames)].Table, handle.Table)
+		handle.Release()
+	}
+
+	_, err := cache.Acquire(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, len(fileNames), cache.OpenFiles())
+
+	cache.Close()
+	assert.Equal(t, 0, cache.OpenFiles())
+	_, err = cache.Acquire(fileNames[0])
+	assert.ErrorIs(t, err, ErrClosed)
+}
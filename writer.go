@@ -0,0 +1,181 @@
+package sstable
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrOutOfOrder = errors.New("sstable: records must be added in strictly increasing key order")
+	// ErrWriterFinished is returned by a TableWriter which has already been finished.
+	ErrWriterFinished = errors.New("sstable: table writer is already finished")
+)
+
+// TableWriter streams records, which must be added in sorted order, to an io.Writer as a table. Each data block
+// is written as soon as it fills, followed on Finish by the filter block, an index block holding the last key and
+// location of each data block, the metadata block and the footer. Only the block being built, the index and the
+// key hashes for the filter are held in memory.
+//
+// Once a write fails the writer returns the same error from every later call.
+type TableWriter struct {
+	w         io.Writer
+	opts      *WriteOptions
+	tableName string
+	offset    uint64
+	block     *blockBuilder
+	index     []indexEntry
+	keyCount  uint64
+	lastKey   []byte
+	// keyHashes holds the hash of every key, and every distinct prefix, added while a filter is being built
+	keyHashes  []uint64
+	lastPrefix []byte
+	err        error
+	finished   bool
+}
+
+// NewTableWriter creates a writer for a table named tableName, laid out according to opts. A nil opts is the same
+// as DefaultWriteOptions.
+func NewTableWriter(w io.Writer, tableName string, opts *WriteOptions) (*TableWriter, error) {
+	if opts == nil {
+		opts = DefaultWriteOptions()
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &TableWriter{
+		w:         w,
+		opts:      opts,
+		tableName: tableName,
+		block:     newBlockBuilder(opts.RestartInterval),
+	}, nil
+}
+
+// Add appends rec to the table, returning ErrOutOfOrder unless its key is greater than every key added before it.
+// The record is encoded before Add returns, so the caller is free to reuse its buffers.
+func (t *TableWriter) Add(rec *Record) error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.finished {
+		return ErrWriterFinished
+	}
+	if err := rec.checkSize(); err != nil {
+		return err
+	}
+	if !rec.Kind.valid() {
+		return fmt.Errorf("sstable: record %q has unknown kind %d", rec.Key, rec.Kind)
+	}
+	if t.keyCount > 0 && bytes.Compare(rec.Key, t.lastKey) <= 0 {
+		return fmt.Errorf("%w: %q added after %q", ErrOutOfOrder, rec.Key, t.lastKey)
+	}
+	if err := t.block.add(rec); err != nil {
+		return err
+	}
+	t.lastKey = append(t.lastKey[:0], rec.Key...)
+	t.keyCount++
+	if t.opts.BloomBitsPerKey > 0 {
+		t.keyHashes = append(t.keyHashes, bloomHash(rec.Key))
+		t.addPrefix(rec.Key)
+	}
+	if t.block.estimatedSize() >= t.opts.BlockSize {
+		return t.flushBlock()
+	}
+	return nil
+}
+
+// addPrefix adds the prefix of key to the filter. Keys arrive sorted, so keys sharing a prefix are adjacent and
+// each prefix only needs adding once.
+func (t *TableWriter) addPrefix(key []byte) {
+	if t.opts.PrefixExtractor == nil {
+		return
+	}
+	prefix, ok := t.opts.PrefixExtractor.Prefix(key)
+	if !ok || (t.lastPrefix != nil && bytes.Equal(prefix, t.lastPrefix)) {
+		return
+	}
+	t.keyHashes = append(t.keyHashes, bloomHash(prefix))
+	t.lastPrefix = append(t.lastPrefix[:0], prefix...)
+}
+
+func (t *TableWriter) flushBlock() error {
+	if t.block.empty() {
+		return nil
+	}
+	lastKey := append([]byte(nil), t.lastKey...)
+	handle, err := t.writeBlock(t.block.finish(), t.opts.Compression)
+	if err != nil {
+		return err
+	}
+	t.index = append(t.index, indexEntry{LastKey: lastKey, Handle: handle})
+	return nil
+}
+
+func (t *TableWriter) writeBlock(raw []byte, codec Codec) (blockHandle, error) {
+	block, err := frameBlock(raw, codec)
+	if err != nil {
+		t.err = err
+		return blockHandle{}, err
+	}
+	handle := blockHandle{Offset: t.offset, Size: uint64(len(block))}
+	if err := t.write(block); err != nil {
+		return blockHandle{}, err
+	}
+	return handle, nil
+}
+
+func (t *TableWriter) write(contents []byte) error {
+	if _, err := t.w.Write(contents); err != nil {
+		t.err = err
+		return err
+	}
+	t.offset += uint64(len(contents))
+	return nil
+}
+
+// Finish flushes any pending data block, then writes the filter, index, metadata and footer. These are never
+// compressed as they are read once when a table is opened. It returns the metadata written to the table.
+func (t *TableWriter) Finish() (*TableMeta, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.finished {
+		return nil, ErrWriterFinished
+	}
+	t.finished = true
+	if err := t.flushBlock(); err != nil {
+		return nil, err
+	}
+	f := &footer{Version: formatVersion}
+
+	var err error
+	if t.opts.BloomBitsPerKey > 0 {
+		f.Filter, err = t.writeBlock(newBloomFilter(t.keyHashes, t.opts.BloomBitsPerKey), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.Index, err = t.writeBlock(encodeIndex(t.index), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := NewTableMeta(t.tableName, t.keyCount)
+	meta.BlockSize = uint32(t.opts.BlockSize)
+	if t.opts.PrefixExtractor != nil && t.opts.BloomBitsPerKey > 0 {
+		meta.PrefixExtractor = []byte(t.opts.PrefixExtractor.Name)
+	}
+	metaBytes, err := meta.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	f.Meta, err = t.writeBlock(metaBytes, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.write(f.ToBytes()); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
@@ -0,0 +1,117 @@
+package sstable
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestTableWriter(t *testing.T) {
+	testTableName := "TestTableWriter"
+	file, err := os.Create(testTableName)
+	require.NoError(t, err)
+	defer os.Remove(testTableName)
+
+	opts := DefaultWriteOptions()
+	opts.BlockSize = 256
+	writer, err := NewTableWriter(file, "ExampleTest", opts)
+	require.NoError(t, err)
+
+	// the key and value buffers are reused for every record
+	key := make([]byte, 8)
+	value := make([]byte, 10)
+	for i := 0; i < 1000; i++ {
+		copy(key, fmt.Sprintf("key-%04d", i))
+		copy(value, fmt.Sprintf("value-%04d", i))
+		require.NoError(t, writer.Add(NewRecordWithCount(key, value, uint64(i))))
+	}
+	meta, err := writer.Finish()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), meta.KeyCount)
+	require.NoError(t, file.Close())
+
+	diskTable, err := NewDiskTable(testTableName)
+	require.NoError(t, err)
+	defer diskTable.Close()
+	assert.Equal(t, meta.KeyCount, diskTable.TableMeta.KeyCount)
+	assert.Equal(t, meta.TableName, diskTable.TableMeta.TableName)
+	assert.Greater(t, len(diskTable.index), 1)
+	for i := 0; i < 1000; i++ {
+		rec, err := diskTable.Get([]byte(fmt.Sprintf("key-%04d", i)))
+		require.NoError(t, err)
+		require.NotNil(t, rec)
+		assert.Equal(t, []byte(fmt.Sprintf("value-%04d", i)), rec.Value)
+	}
+}
+
+func TestTableWriter_OutOfOrder(t *testing.T) {
+	writer, err := NewTableWriter(&failingWriter{}, "ExampleTest", DefaultWriteOptions())
+	require.NoError(t, err)
+	require.NoError(t, writer.Add(NewRecordWithCount([]byte("B"), []byte("Bravo"), 1)))
+	assert.ErrorIs(t, writer.Add(NewRecordWithCount([]byte("A"), []byte("Alpha"), 2)), ErrOutOfOrder)
+	assert.ErrorIs(t, writer.Add(NewRecordWithCount([]byte("B"), []byte("Bravo"), 3)), ErrOutOfOrder)
+	require.NoError(t, writer.Add(NewRecordWithCount([]byte("C"), []byte("Charlie"), 4)))
+}
+
+func TestTableWriter_UnknownKind(t *testing.T) {
+	writer, err := NewTableWriter(io.Discard, "ExampleTest", DefaultWriteOptions())
+	require.NoError(t, err)
+	rec := NewRecordWithCount([]byte("A"), []byte("Alpha"), 1)
+	rec.Kind = KindDelete + 1
+	assert.Error(t, writer.Add(rec))
+
+	// the rejected record leaves the writer as it was
+	require.NoError(t, writer.Add(NewRecordWithCount([]byte("A"), []byte("Alpha"), 1)))
+	meta, err := writer.Finish()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), meta.KeyCount)
+}
+
+func TestTableWriter_Finished(t *testing.T) {
+	writer, err := NewTableWriter(io.Discard, "ExampleTest", DefaultWriteOptions())
+	require.NoError(t, err)
+	require.NoError(t, writer.Add(NewRecordWithCount([]byte("A"), []byte("Alpha"), 1)))
+	_, err = writer.Finish()
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, writer.Add(NewRecordWithCount([]byte("B"), []byte("Bravo"), 2)), ErrWriterFinished)
+	_, err = writer.Finish()
+	assert.ErrorIs(t, err, ErrWriterFinished)
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+func TestTableWriter_WriteError(t *testing.T) {
+	errDiskFull := errors.New("disk full")
+	w := &failingWriter{}
+	opts := DefaultWriteOptions()
+	opts.BlockSize = 64
+	writer, err := NewTableWriter(w, "ExampleTest", opts)
+	require.NoError(t, err)
+
+	w.err = errDiskFull
+	for i := 0; err == nil; i++ {
+		err = writer.Add(NewRecordWithCount([]byte(fmt.Sprintf("key-%04d", i)), []byte("value"), uint64(i)))
+	}
+	assert.ErrorIs(t, err, errDiskFull)
+	_, err = writer.Finish()
+	assert.ErrorIs(t, err, errDiskFull)
+}
+
+func TestNewTableWriter_InvalidOptions(t *testing.T) {
+	_, err := NewTableWriter(&failingWriter{}, "ExampleTest", &WriteOptions{})
+	assert.Error(t, err)
+}